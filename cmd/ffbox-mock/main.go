@@ -0,0 +1,77 @@
+// Command ffbox-mock は、freee API のモックサーバーを起動します。
+//
+// ネットワークや freee アカウントなしで ffbox を試したり、スクリプトを検証したりするために使用します。
+// 証憑（ファイルボックス）、事業所、ユーザー情報のエンドポイントをメモリ上で再現します。
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi/fake"
+)
+
+// version は、ビルド時に ldflags 経由で設定されます。
+var version = "dev"
+
+var app = &cli.Command{
+	Name:    "ffbox-mock",
+	Usage:   "freee API のモックサーバーを起動します",
+	Version: version,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "addr",
+			Usage: "待ち受けるアドレス",
+			Value: "127.0.0.1:8089",
+		},
+		&cli.StringFlag{
+			Name:  "token",
+			Usage: "受け付けるアクセストークン（省略時は任意のトークンを受け付けます）",
+		},
+		&cli.BoolFlag{
+			Name:  "empty",
+			Usage: "サンプルデータを登録せずに起動します",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		srv := fake.New()
+		srv.Token = cmd.String("token")
+		if !cmd.Bool("empty") {
+			srv.SeedSampleData()
+		}
+
+		ln, err := net.Listen("tcp", cmd.String("addr"))
+		if err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+		token := srv.Token
+		if token == "" {
+			token = "dummy"
+		}
+		fmt.Fprintf(os.Stderr, "freee API モックサーバーを起動しました: http://%s/\n", ln.Addr())
+		fmt.Fprintf(os.Stderr, "\n  FFBOX_API_ENDPOINT=http://%s/ FFBOX_ACCESS_TOKEN=%s ffbox list\n\n", ln.Addr(), token)
+
+		hs := &http.Server{Handler: srv}
+		go func() {
+			<-ctx.Done()
+			hs.Close()
+		}()
+		if err := hs.Serve(ln); err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("serve: %w", err)
+		}
+		return nil
+	},
+}
+
+func main() {
+	ctx := context.Background()
+	if err := app.Run(ctx, os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/audit"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+var (
+	flagAttachDeal = &cli.Int64Flag{
+		Name:  "deal",
+		Usage: "証憑を添付する取引ID",
+	}
+	flagAttachManualJournal = &cli.Int64Flag{
+		Name:  "manual-journal",
+		Usage: "証憑を添付する振替伝票ID",
+	}
+)
+
+var cmdAttach = &cli.Command{
+	Category:  "receipts",
+	Name:      "attach",
+	Usage:     "証憑ファイルを取引または振替伝票に添付します",
+	ArgsUsage: "(--deal <deal-id> | --manual-journal <manual-journal-id>) <receipt-ids...>",
+	Description: `指定した取引または振替伝票に証憑ファイルを添付します。
+
+取引・振替伝票にすでに添付されている証憑は、そのまま残ります。
+すでに添付済みの証憑を指定した場合は、何もしません。`,
+	Flags:  []cli.Flag{flagAttachDeal, flagAttachManualJournal},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		return runLinkReceipts(ctx, cmd, cmd.Int64(flagAttachDeal.Name), cmd.Int64(flagAttachManualJournal.Name), attachReceipts)
+	},
+}
+
+var (
+	flagDetachDeal = &cli.Int64Flag{
+		Name:  "deal",
+		Usage: "証憑の添付を解除する取引ID",
+	}
+	flagDetachManualJournal = &cli.Int64Flag{
+		Name:  "manual-journal",
+		Usage: "証憑の添付を解除する振替伝票ID",
+	}
+)
+
+var cmdDetach = &cli.Command{
+	Category:  "receipts",
+	Name:      "detach",
+	Usage:     "取引または振替伝票から証憑ファイルの添付を解除します",
+	ArgsUsage: "(--deal <deal-id> | --manual-journal <manual-journal-id>) <receipt-ids...>",
+	Description: `指定した取引または振替伝票から証憑ファイルの添付を解除します。
+
+証憑ファイル自体は削除されず、ファイルボックスに残ります。`,
+	Flags:  []cli.Flag{flagDetachDeal, flagDetachManualJournal},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		return runLinkReceipts(ctx, cmd, cmd.Int64(flagDetachDeal.Name), cmd.Int64(flagDetachManualJournal.Name), detachReceipts)
+	},
+}
+
+// receiptsEditor は、添付先 target（"取引 123" など）に添付されている証憑IDの一覧 current と、
+// 引数で指定された証憑ID ids から、更新後の証憑IDの一覧を返します。変更がない場合は changed に false を返します。
+type receiptsEditor func(target string, current, ids []int64) (next []int64, changed bool)
+
+// attachReceipts は、ids のうち未添付の証憑を current に追加します。
+func attachReceipts(target string, current, ids []int64) ([]int64, bool) {
+	for _, id := range ids {
+		if slices.Contains(current, id) {
+			fmt.Fprintf(os.Stderr, "証憑 %d はすでに %s に添付されています\n", id, target)
+		}
+	}
+	next := freeeapi.AddIDs(current, ids)
+	return next, len(next) != len(current)
+}
+
+// detachReceipts は、current から ids の証憑を取り除きます。
+func detachReceipts(target string, current, ids []int64) ([]int64, bool) {
+	for _, id := range ids {
+		if !slices.Contains(current, id) {
+			fmt.Fprintf(os.Stderr, "証憑 %d は %s に添付されていません\n", id, target)
+		}
+	}
+	next := freeeapi.RemoveIDs(current, ids)
+	return next, len(next) != len(current)
+}
+
+// runLinkReceipts は、取引 dealID または振替伝票 manualJournalID に添付されている証憑の一覧を edit で変更します。
+// dealID と manualJournalID は、どちらか一方だけを指定します。
+func runLinkReceipts(ctx context.Context, cmd *cli.Command, dealID, manualJournalID int64, edit receiptsEditor) error {
+	switch {
+	case dealID == 0 && manualJournalID == 0:
+		return fmt.Errorf("--deal で取引IDを、または --manual-journal で振替伝票IDを指定してください")
+	case dealID != 0 && manualJournalID != 0:
+		return fmt.Errorf("--deal と --manual-journal は同時に指定できません")
+	}
+	ids, err := parseReceiptIDs(cmd.Args().Slice())
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("証憑IDを指定してください")
+	}
+
+	freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+	if err != nil {
+		return err
+	}
+	if dealID != 0 {
+		return updateDealReceipts(ctx, freeeapiClient, companyID, dealID, ids, edit)
+	}
+	return updateManualJournalReceipts(ctx, freeeapiClient, companyID, manualJournalID, ids, edit)
+}
+
+// updateDealReceipts は、取引を取得して添付されている証憑の一覧を edit で変更し、取引を更新します。
+func updateDealReceipts(ctx context.Context, client *freeeapi.Client, companyID, dealID int64, ids []int64, edit receiptsEditor) error {
+	resp, err := client.GetDealWithResponse(ctx, dealID, &freeeapigen.GetDealParams{CompanyId: companyID})
+	if err != nil {
+		return fmt.Errorf("get deal ID %d: %w", dealID, err)
+	}
+	if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+		return fmt.Errorf("got unexpected response for deal ID %d: %s", dealID, resp.Status())
+	}
+	params, err := freeeapi.NewDealUpdateParams(&resp.JSON200.Deal)
+	if err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("取引 %d", dealID)
+	current := *params.ReceiptIds
+	next, changed := edit(target, current, ids)
+	if !changed {
+		return nil
+	}
+	params.CompanyId = companyID
+	params.ReceiptIds = &next
+
+	updated, err := client.UpdateDealWithResponse(ctx, dealID, *params)
+	if err != nil {
+		return fmt.Errorf("update deal ID %d: %w", dealID, err)
+	}
+	if updated.StatusCode() != http.StatusOK || updated.JSON200 == nil {
+		return fmt.Errorf("got unexpected response for deal ID %d: %s", dealID, updated.Status())
+	}
+	invalidateReceiptLinks(companyID)
+	if err := recordAudit(ctx, receiptsEditAuditEntry(companyID, fmt.Sprintf("%s:%d", linkTypeDeal, dealID), current, next), params); err != nil {
+		return err
+	}
+	fmt.Printf("%s の証憑: %s\n", target, formatIDs(freeeapi.DealReceiptIDs(&updated.JSON200.Deal)))
+	return nil
+}
+
+// updateManualJournalReceipts は、振替伝票を取得して添付されている証憑の一覧を edit で変更し、振替伝票を更新します。
+func updateManualJournalReceipts(ctx context.Context, client *freeeapi.Client, companyID, journalID int64, ids []int64, edit receiptsEditor) error {
+	resp, err := client.GetManualJournalWithResponse(ctx, journalID, &freeeapigen.GetManualJournalParams{CompanyId: companyID})
+	if err != nil {
+		return fmt.Errorf("get manual journal ID %d: %w", journalID, err)
+	}
+	if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+		return fmt.Errorf("got unexpected response for manual journal ID %d: %s", journalID, resp.Status())
+	}
+	params, err := freeeapi.NewManualJournalUpdateParams(&resp.JSON200.ManualJournal)
+	if err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("振替伝票 %d", journalID)
+	current := *params.ReceiptIds
+	next, changed := edit(target, current, ids)
+	if !changed {
+		return nil
+	}
+	params.CompanyId = companyID
+	params.ReceiptIds = &next
+
+	updated, err := client.UpdateManualJournalWithResponse(ctx, journalID, *params)
+	if err != nil {
+		return fmt.Errorf("update manual journal ID %d: %w", journalID, err)
+	}
+	if updated.StatusCode() != http.StatusOK || updated.JSON200 == nil {
+		return fmt.Errorf("got unexpected response for manual journal ID %d: %s", journalID, updated.Status())
+	}
+	invalidateReceiptLinks(companyID)
+	if err := recordAudit(ctx, receiptsEditAuditEntry(companyID, fmt.Sprintf("%s:%d", linkTypeManualJournal, journalID), current, next), params); err != nil {
+		return err
+	}
+	fmt.Printf("%s の証憑: %s\n", target, formatIDs(freeeapi.ManualJournalReceiptIDs(&updated.JSON200.ManualJournal)))
+	return nil
+}
+
+// receiptsEditAuditEntry は、添付先 target の証憑を current から next に変更したときの監査ログのエントリを返します。
+// 追加した証憑があれば attach、そうでなければ detach として、追加・削除した証憑IDを記録します。
+func receiptsEditAuditEntry(companyID int64, target string, current, next []int64) audit.Entry {
+	e := audit.Entry{CompanyID: companyID, Target: target, Operation: auditOpAttach}
+	for _, id := range next {
+		if !slices.Contains(current, id) {
+			e.ReceiptIDs = append(e.ReceiptIDs, id)
+		}
+	}
+	if len(e.ReceiptIDs) == 0 {
+		e.Operation = auditOpDetach
+		for _, id := range current {
+			if !slices.Contains(next, id) {
+				e.ReceiptIDs = append(e.ReceiptIDs, id)
+			}
+		}
+	}
+	return e
+}
+
+// parseReceiptIDs は、コマンドライン引数で指定された証憑IDを解析します。
+func parseReceiptIDs(args []string) ([]int64, error) {
+	ids := make([]int64, 0, len(args))
+	for i, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid receipt ID[%d]: %q", i, arg)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// formatIDs は、ID の一覧をカンマ区切りの文字列にします。空の場合は "(none)" を返します。
+func formatIDs(ids []int64) string {
+	if len(ids) == 0 {
+		return "(none)"
+	}
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/audit"
+	"github.com/micheam/freee-filebox-ctl/internal/config"
+)
+
+// 監査ログに記録する操作の名前です。
+const (
+	auditOpUpload               = "upload"
+	auditOpUpdate               = "update"
+	auditOpAttach               = "attach"
+	auditOpDetach               = "detach"
+	auditOpDealCreate           = "deal.create"
+	auditOpExpenseCreate        = "expense.create"
+	auditOpPaymentRequestCreate = "payment-request.create"
+)
+
+var cmdAudit = &cli.Command{
+	Name:  "audit",
+	Usage: "ffbox で行った変更の監査ログを確認します",
+	Description: `証憑のアップロード・更新や添付など、ffbox で行った変更を伴う操作は監査ログに記録されます。
+
+監査ログは JSON Lines 形式で、各エントリには日時・OS のユーザー・事業所ID・証憑ID・操作・
+アップロードしたファイルのハッシュ・送信したパラメータが記録されます。
+各エントリは直前のエントリのハッシュを含むため、途中のエントリの改ざんや削除を検出できます。
+
+監査ログの場所は、設定ファイルの [audit] log_file で変更できます。
+省略時は $XDG_STATE_HOME/ffbox/audit.jsonl（または $HOME/.local/state/ffbox/audit.jsonl）です。`,
+	Before: loadAppConfig,
+	Commands: []*cli.Command{
+		cmdAuditVerify,
+		cmdAuditLog,
+	},
+}
+
+var cmdAuditVerify = &cli.Command{
+	Name:  "verify",
+	Usage: "監査ログのハッシュチェーンを検証します",
+	Description: `監査ログの各エントリのハッシュと、直前のエントリとのつながりを検証します。
+改ざんや削除が見つかった場合は、その行を表示して終了コード 1 で終了します。
+
+末尾のエントリの削除は監査ログだけでは検出できないため、
+表示される最終ハッシュを別の場所に控えておくと、後から照合できます。`,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		path, err := auditLogPath(ctx)
+		if err != nil {
+			return err
+		}
+		n, last, err := audit.Verify(path)
+		if err != nil {
+			return fmt.Errorf("監査ログの検証に失敗しました（改ざんされている可能性があります）: %w", err)
+		}
+		if last == nil {
+			fmt.Printf("監査ログにエントリがありません: %s\n", path)
+			return nil
+		}
+		fmt.Printf("監査ログを検証しました: %d 件、最終エントリ %d（%s）\n", n, last.Seq, last.Time.Local().Format(time.DateTime))
+		fmt.Printf("最終ハッシュ: %s\n", last.Hash)
+		return nil
+	},
+}
+
+var (
+	flagAuditLogReceipt = &cli.Int64Flag{
+		Name:  "receipt",
+		Usage: "指定した証憑IDに関するエントリだけを表示します",
+	}
+	flagAuditLogOperation = &cli.StringFlag{
+		Name:  "operation",
+		Usage: "指定した操作のエントリだけを表示します (upload, update, attach, detach, deal.create, expense.create, payment-request.create)",
+	}
+	flagAuditLogUser = &cli.StringFlag{
+		Name:  "user",
+		Usage: "指定したユーザーのエントリだけを表示します",
+	}
+	flagAuditLogSince = &cli.StringFlag{
+		Name:      "since",
+		Usage:     "この日以降のエントリだけを表示します (yyyy-mm-dd)",
+		Validator: validateDate,
+	}
+	flagAuditLogUntil = &cli.StringFlag{
+		Name:      "until",
+		Usage:     "この日までのエントリだけを表示します (yyyy-mm-dd)",
+		Validator: validateDate,
+	}
+	flagAuditLogFormat = &cli.StringFlag{
+		Name:  "format",
+		Usage: "出力フォーマット (table, json)",
+		Value: "table",
+	}
+)
+
+var cmdAuditLog = &cli.Command{
+	Name:  "log",
+	Usage: "監査ログのエントリを表示します",
+	Description: `監査ログのエントリを古い順に表示します。--format json では、記録されたエントリをそのまま出力します。
+
+  ffbox audit log --receipt 123
+  ffbox audit log --operation upload --since 2025-04-01 --format json`,
+	Flags: []cli.Flag{
+		flagAuditLogReceipt,
+		flagAuditLogOperation,
+		flagAuditLogUser,
+		flagAuditLogSince,
+		flagAuditLogUntil,
+		flagAuditLogFormat,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		format := cmd.String(flagAuditLogFormat.Name)
+		switch format {
+		case "table", "json":
+			// valid
+		default:
+			return fmt.Errorf("format は table か json を指定してください")
+		}
+		path, err := auditLogPath(ctx)
+		if err != nil {
+			return err
+		}
+		entries, err := audit.Read(path)
+		if err != nil {
+			return fmt.Errorf("監査ログの読み込みに失敗しました: %w", err)
+		}
+
+		receiptID := cmd.Int64(flagAuditLogReceipt.Name)
+		operation := cmd.String(flagAuditLogOperation.Name)
+		userName := cmd.String(flagAuditLogUser.Name)
+		since := cmd.String(flagAuditLogSince.Name)
+		until := cmd.String(flagAuditLogUntil.Name)
+		entries = slices.DeleteFunc(entries, func(e audit.Entry) bool {
+			date := e.Time.Local().Format(time.DateOnly)
+			return (receiptID != 0 && !slices.Contains(e.ReceiptIDs, receiptID)) ||
+				(operation != "" && e.Operation != operation) ||
+				(userName != "" && e.User != userName) ||
+				(since != "" && date < since) ||
+				(until != "" && date > until)
+		})
+
+		if format == "json" {
+			for _, e := range entries {
+				b, err := json.Marshal(e)
+				if err != nil {
+					return fmt.Errorf("marshal audit entry: %w", err)
+				}
+				fmt.Println(string(b))
+			}
+			return nil
+		}
+		if len(entries) == 0 {
+			fmt.Println("No entries found.")
+			return nil
+		}
+		return printAuditEntries(os.Stdout, entries)
+	},
+}
+
+// printAuditEntries は、監査ログのエントリを表形式で出力します。
+func printAuditEntries(w io.Writer, entries []audit.Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEQ\tTIME\tUSER\tCOMPANY\tOPERATION\tTARGET\tRECEIPTS")
+	for _, e := range entries {
+		receipts := make([]string, len(e.ReceiptIDs))
+		for i, id := range e.ReceiptIDs {
+			receipts[i] = strconv.FormatInt(id, 10)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			e.Seq, e.Time.Local().Format(time.DateTime), e.User, e.CompanyID, e.Operation, e.Target, strings.Join(receipts, ","))
+	}
+	return tw.Flush()
+}
+
+// auditLogPath は、設定に基づいて監査ログのパスを返します。
+// 相対パスは、その値を設定した設定ファイルのディレクトリからの相対パスとして扱います。
+func auditLogPath(ctx context.Context) (string, error) {
+	appConfig := config.FromContext(ctx)
+	if appConfig == nil {
+		panic("app config is not set in context")
+	}
+	path := appConfig.Audit.LogFile
+	if path == "" {
+		dir := config.StateDir()
+		if dir == "" {
+			return "", fmt.Errorf("監査ログの場所が見つかりません: $XDG_STATE_HOME または $HOME を設定するか、[audit] log_file を指定してください")
+		}
+		return filepath.Join(dir, "audit.jsonl"), nil
+	}
+	return appConfig.ResolvePath("audit.log_file", path), nil
+}
+
+// recordAudit は、変更を伴う操作を監査ログに記録します。
+// params は送信したパラメータで、JSON として記録します。
+//
+// 操作自体はすでに完了しているため、記録に失敗した場合はその旨がわかるエラーを返します。
+func recordAudit(ctx context.Context, e audit.Entry, params any) error {
+	path, err := auditLogPath(ctx)
+	if err != nil {
+		return fmt.Errorf("操作は完了しましたが、監査ログに記録できませんでした: %w", err)
+	}
+	if params != nil {
+		if e.Params, err = json.Marshal(params); err != nil {
+			return fmt.Errorf("操作は完了しましたが、監査ログに記録できませんでした: marshal params: %w", err)
+		}
+	}
+	e.Time = time.Now()
+	e.User = currentUserName()
+	if _, err := audit.Append(path, e); err != nil {
+		return fmt.Errorf("操作は完了しましたが、監査ログに記録できませんでした: %w", err)
+	}
+	return nil
+}
+
+// currentUserName は、OS のユーザー名を返します。
+func currentUserName() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
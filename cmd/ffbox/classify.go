@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/config"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+	"github.com/micheam/freee-filebox-ctl/internal/rules"
+)
+
+var (
+	flagClassifySince = &cli.StringFlag{
+		Name:      "since",
+		Usage:     "分類する証憑の登録日の開始日 (yyyy-mm-dd)",
+		Required:  true,
+		Validator: validateDate,
+	}
+	flagClassifyUntil = &cli.StringFlag{
+		Name:      "until",
+		Usage:     "分類する証憑の登録日の終了日 (yyyy-mm-dd)",
+		Value:     time.Now().Format(time.DateOnly),
+		Validator: validateDate,
+	}
+	flagClassifyRules = &cli.StringFlag{
+		Name:  "rules",
+		Usage: "ルールファイルのパス（省略時は設定ファイルの classify.rules_file）",
+	}
+	flagClassifyDryRun = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "証憑を更新せずに、変更内容だけを表示します",
+	}
+)
+
+var cmdClassify = &cli.Command{
+	Category:  "receipts",
+	Name:      "classify",
+	Usage:     "ルールファイルに従って証憑ファイルを分類します",
+	ArgsUsage: "--since <yyyy-mm-dd>",
+	Description: `登録日が期間内の証憑にルールファイルのルールを適用し、書類の種類・適格請求書等・メモを更新します。
+変更がある証憑ごとに、変更前後の値を表示します。--dry-run では更新しません。
+
+ルールファイルは TOML 形式で、条件（match）に一致した証憑に値（set）を設定します。
+ルールは上から順に評価され、各項目には最初に一致したルールの値が使われます。
+タグはメモの末尾に #タグ として追加されます。
+
+  [[rule]]
+  name = "aws"
+  [rule.match]
+  partner = "(?i)amazon web services"  # 発行元の正規表現
+  min_amount = 1000                    # 金額の範囲（max_amount も指定可能）
+  mime_type = "application/pdf"        # MIME タイプ（image/* のようなパターンも可能）
+  origin = "mail"                      # アップロード元種別
+  filename = "*_aws_*.pdf"             # ファイル名のパターン（アップロード時のみ）
+  [rule.set]
+  document_type = "invoice"
+  qualified_invoice = "qualified"
+  description = "AWS 利用料"
+  tags = ["cloud"]
+
+ルールファイルは ffbox upload と ffbox watch でも、アップロードする証憑に適用されます。
+
+  ffbox classify --since 2025-04-01 --dry-run`,
+	Flags: []cli.Flag{
+		flagClassifySince,
+		flagClassifyUntil,
+		flagClassifyRules,
+		flagClassifyDryRun,
+	},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		rs, err := loadRules(ctx, cmd.String(flagClassifyRules.Name), true)
+		if err != nil {
+			return err
+		}
+		start, err := time.Parse(time.DateOnly, cmd.String(flagClassifySince.Name))
+		if err != nil {
+			return fmt.Errorf("parse --since: %w", err)
+		}
+		end, err := time.Parse(time.DateOnly, cmd.String(flagClassifyUntil.Name))
+		if err != nil {
+			return fmt.Errorf("parse --until: %w", err)
+		}
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+		receipts, err := listReceipts(ctx, freeeapiClient, companyID, start, end, nil)
+		if err != nil {
+			return err
+		}
+
+		dryRun := cmd.Bool(flagClassifyDryRun.Name)
+		changed := 0
+		for _, r := range receipts {
+			if r.Status != freeeapigen.ReceiptStatusConfirmed {
+				continue
+			}
+			c, ok := classifyReceipt(rs, &r)
+			if !ok {
+				continue
+			}
+			changed++
+			c.print(os.Stdout, r.Id)
+			if dryRun {
+				continue
+			}
+			c.params.CompanyId = companyID
+			if _, err := updateReceipt(ctx, freeeapiClient, companyID, r.Id, c.params); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			fmt.Printf("更新対象の証憑: %d 件（--dry-run のため更新していません）\n", changed)
+		} else {
+			fmt.Printf("証憑 %d 件を更新しました\n", changed)
+		}
+		return nil
+	},
+}
+
+// receiptClassification は、ルールを適用した証憑の更新内容です。
+type receiptClassification struct {
+	// rules は、一致したルールの名前です。
+	rules []string
+	// diffs は、変更する項目の変更前後の値です。
+	diffs []fieldDiff
+	// params は、変更する項目だけを設定した更新のパラメータです。
+	params freeeapigen.ReceiptUpdateParams
+}
+
+// fieldDiff は、項目の変更前後の値です。
+type fieldDiff struct {
+	name     string
+	old, new *string
+}
+
+// classifyReceipt は、証憑にルールを適用し、変更がある場合は ok に true を返します。
+func classifyReceipt(rs *rules.Rules, r *freeeapigen.Receipt) (c receiptClassification, ok bool) {
+	target := rules.Target{MimeType: r.MimeType, Origin: string(r.Origin)}
+	if m := r.ReceiptMetadatum; m != nil {
+		target.Partner = deref(m.PartnerName, "")
+		target.Amount = m.Amount
+	}
+	res, ok := rs.Apply(target)
+	if !ok {
+		return c, false
+	}
+	c.rules = res.Rules
+
+	if v := res.DocumentType; v != "" && v != string(deref(r.DocumentType, "")) {
+		c.diffs = append(c.diffs, fieldDiff{"document_type", (*string)(r.DocumentType), &v})
+		c.params.DocumentType = ptr(freeeapigen.ReceiptUpdateParamsDocumentType(v))
+	}
+	if v := res.QualifiedInvoice; v != "" && v != string(deref(r.QualifiedInvoice, "")) {
+		c.diffs = append(c.diffs, fieldDiff{"qualified_invoice", (*string)(r.QualifiedInvoice), &v})
+		c.params.QualifiedInvoice = ptr(freeeapigen.ReceiptUpdateParamsQualifiedInvoice(v))
+	}
+	if res.Description != "" || len(res.Tags) > 0 {
+		if v := res.Describe(deref(r.Description, "")); v != deref(r.Description, "") {
+			c.diffs = append(c.diffs, fieldDiff{"description", r.Description, &v})
+			c.params.Description = &v
+		}
+	}
+	return c, len(c.diffs) > 0
+}
+
+// print は、更新内容を出力します。
+func (c *receiptClassification) print(w io.Writer, receiptID int64) {
+	fmt.Fprintf(w, "証憑 %d（ルール: %s）\n", receiptID, strings.Join(c.rules, ", "))
+	format := func(v *string) string {
+		if v == nil {
+			return "null"
+		}
+		return strconv.Quote(*v)
+	}
+	for _, d := range c.diffs {
+		fmt.Fprintf(w, "  %s: %s -> %s\n", d.name, format(d.old), format(d.new))
+	}
+}
+
+// applyUploadRules は、アップロードする証憑にルールを適用し、フラグで指定されていない項目を設定します。
+// 一致したルールがない場合は何もしません。
+func applyUploadRules(rs *rules.Rules, params *freeeapigen.ReceiptCreateParams, content []byte) {
+	target := rules.Target{
+		Partner:  deref(params.ReceiptMetadatumPartnerName, ""),
+		Amount:   params.ReceiptMetadatumAmount,
+		MimeType: http.DetectContentType(content),
+		Filename: params.Receipt.Filename(),
+		Origin:   string(freeeapigen.PublicApi),
+	}
+	res, ok := rs.Apply(target)
+	if !ok {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "分類ルールを適用します: %s\n", strings.Join(res.Rules, ", "))
+	if v := res.DocumentType; v != "" && params.DocumentType == nil {
+		params.DocumentType = ptr(freeeapigen.ReceiptCreateParamsDocumentType(v))
+	}
+	if v := res.QualifiedInvoice; v != "" && params.QualifiedInvoice == nil {
+		params.QualifiedInvoice = ptr(freeeapigen.ReceiptCreateParamsQualifiedInvoice(v))
+	}
+	if params.Description != nil {
+		// フラグで指定したメモを優先し、タグだけを追加する。
+		res.Description = ""
+	}
+	if res.Description != "" || len(res.Tags) > 0 {
+		params.Description = ptr(res.Describe(deref(params.Description, "")))
+	}
+}
+
+// loadRules は、ルールファイルを読み込みます。path が空の場合は、設定ファイルの classify.rules_file を使用します。
+// 設定ファイルの相対パスは、その値を設定した設定ファイルのディレクトリからの相対パスとして扱います。
+// ファイルがない場合、required であればエラーを、そうでなければ nil を返します。
+func loadRules(ctx context.Context, path string, required bool) (*rules.Rules, error) {
+	if path == "" {
+		appConfig := config.FromContext(ctx)
+		if appConfig == nil {
+			panic("app config is not set in context")
+		}
+		path = appConfig.Classify.RulesFile
+		if path == "" {
+			if required {
+				return nil, fmt.Errorf("ルールファイルを --rules または設定ファイルの classify.rules_file で指定してください")
+			}
+			return nil, nil
+		}
+		path = appConfig.ResolvePath("classify.rules_file", path)
+	}
+	rs, err := rules.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if required {
+			return nil, fmt.Errorf("ルールファイルが見つかりません: %s", path)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ルールファイル %s を読み込めませんでした: %w", path, err)
+	}
+	return rs, nil
+}
@@ -5,41 +5,277 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/config"
+	"github.com/micheam/freee-filebox-ctl/internal/formatter"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
 )
 
-var cmdCompaniesList = &cli.Command{
+var cmdCompanies = &cli.Command{
 	Name:   "companies",
 	Usage:  "所属するfreee事業所の一覧を表示します",
+	Flags:  companiesListFlags(),
 	Before: loadAppConfig,
+	Action: runCompaniesList,
+	Commands: []*cli.Command{
+		cmdCompaniesList,
+		cmdCompaniesShow,
+		cmdCompaniesUse,
+	},
+}
+
+// companiesListFlags は、事業所一覧の表示に使用するフラグを生成します。
+// companies と companies list の両方で使用するため、呼び出しごとに新しいフラグを返します。
+func companiesListFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "出力フォーマット (table, json, csv)",
+			Value: "table",
+		},
+		&cli.StringFlag{
+			Name:  "fields",
+			Usage: "表示するフィールドのカンマ区切りリスト (例: id,display_name,role)",
+		},
+		&cli.BoolFlag{
+			Name:  "list-fields",
+			Usage: "利用可能なフィールドの一覧を表示",
+		},
+	}
+}
+
+var cmdCompaniesList = &cli.Command{
+	Name:   "list",
+	Usage:  "所属するfreee事業所の一覧を表示します",
+	Flags:  companiesListFlags(),
+	Action: runCompaniesList,
+}
+
+// runCompaniesList は、所属する事業所の一覧を表示します。
+// 設定ファイルで既定とされている事業所には印を付けます。
+func runCompaniesList(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Bool("list-fields") {
+		for _, field := range formatter.AvailableCompanyFields() {
+			fmt.Println(field)
+		}
+		return nil
+	}
+
+	format := cmd.String("format")
+	switch format {
+	case "table", "json", "csv":
+		// valid
+	default:
+		return fmt.Errorf("format は table, json, csv のいずれかを指定してください")
+	}
+	fields := splitFields(cmd.String("fields"))
+
+	freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("prepare Freee API client: %w", err)
+	}
+	r, err := getCompanies(ctx, freeeapiClient)
+	if err != nil {
+		return err
+	}
+	if len(r.Companies) == 0 {
+		fmt.Println("事業所が見つかりませんでした")
+		return nil
+	}
+
+	defaultID := config.FromContext(ctx).Freee.CompanyID
+	switch format {
+	case "json":
+		for _, company := range r.Companies {
+			output := formatter.ExtractCompanyFields(&company, company.Id == defaultID, fields)
+			b, err := json.Marshal(output)
+			if err != nil {
+				return fmt.Errorf("marshal company: %w", err)
+			}
+			fmt.Println(string(b))
+		}
+		return nil
+	case "csv":
+		f := formatter.NewCompanyList(os.Stdout, defaultID)
+		if err := f.FormatCSV(r.Companies, fields); err != nil {
+			return fmt.Errorf("format companies: %w", err)
+		}
+		return nil
+	}
+	// Default: table format
+	f := formatter.NewCompanyList(os.Stdout, defaultID)
+	if err := f.FormatWithFields(r.Companies, fields); err != nil {
+		return fmt.Errorf("format companies: %w", err)
+	}
+	return nil
+}
+
+var cmdCompaniesShow = &cli.Command{
+	Name:      "show",
+	Usage:     "指定した事業所の詳細を表示します（省略時は既定の事業所）",
+	ArgsUsage: "[name|id]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "出力フォーマット (table, json)",
+			Value: "table",
+		},
+	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
+		query := strings.TrimSpace(cmd.Args().First())
+		format := cmd.String("format")
+		switch format {
+		case "table", "json":
+			// valid
+		default:
+			return fmt.Errorf("format は table か json を指定してください")
+		}
+
 		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
 		if err != nil {
-			return fmt.Errorf("prepare Freee API client: %w", err)
+			return err
+		}
+		var companyID int64
+		if query != "" {
+			companyID, err = resolveCompanyID(ctx, freeeapiClient, query)
+		} else {
+			companyID, err = detectCompanyID(ctx, cmd, freeeapiClient)
+		}
+		if err != nil {
+			return err
 		}
 
-		resp, err := freeeapiClient.GetCompaniesWithResponse(ctx)
+		resp, err := freeeapiClient.GetCompanyWithResponse(ctx, companyID, &freeeapigen.GetCompanyParams{})
 		if err != nil {
-			return fmt.Errorf("get companies: %w", err)
-		}
-		switch resp.StatusCode() {
-		case http.StatusOK:
-			r := resp.JSON200
-			if r == nil || r.Companies == nil {
-				fmt.Println("事業所が見つかりませんでした")
-				return nil
-			}
-			for _, company := range r.Companies {
-				b, err := json.Marshal(company)
-				if err != nil {
-					return fmt.Errorf("marshal company: %w", err)
-				}
-				fmt.Println(string(b))
+			return fmt.Errorf("get company ID %d: %w", companyID, err)
+		}
+		if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+			return fmt.Errorf("got unexpected response for company ID %d: %s", companyID, resp.Status())
+		}
+
+		if format == "json" {
+			b, err := json.Marshal(resp.JSON200.Company)
+			if err != nil {
+				return fmt.Errorf("marshal company ID %d: %w", companyID, err)
 			}
-		default:
-			return fmt.Errorf("got unexpected response: %s", resp.Status())
+			fmt.Println(string(b))
+			return nil
+		}
+		defaultID := config.FromContext(ctx).Freee.CompanyID
+		f := formatter.NewCompany(os.Stdout, defaultID)
+		if err := f.Format(resp.JSON200); err != nil {
+			return fmt.Errorf("format company ID %d: %w", companyID, err)
 		}
 		return nil
 	},
 }
+
+var cmdCompaniesUse = &cli.Command{
+	Name:      "use",
+	Usage:     "指定した事業所を既定の事業所として設定ファイルに保存します",
+	ArgsUsage: "<name|id>",
+	Description: `事業所ID、事業所名、または事業所名（カナ）で指定した事業所を、既定の事業所として設定ファイルに保存します。
+
+--profile が指定されている場合は、そのプロファイルの company_id を更新します。`,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		query := strings.TrimSpace(cmd.Args().First())
+		if query == "" {
+			return fmt.Errorf("事業所名または事業所IDを指定してください")
+		}
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := resolveCompanyID(ctx, freeeapiClient, query)
+		if err != nil {
+			return err
+		}
+		profile := cmd.String(flagProfile.Name)
+		if err := config.WriteCompanyID(profile, companyID); err != nil {
+			return fmt.Errorf("設定ファイルの更新に失敗しました: %w", err)
+		}
+		fmt.Printf("既定の事業所を %d に設定しました: %q\n", companyID, config.ConfigPath())
+		return nil
+	},
+}
+
+// getCompanies は、ログインユーザーが所属する事業所の一覧を取得します。
+func getCompanies(ctx context.Context, client *freeeapi.Client) (*freeeapigen.CompanyIndexResponse, error) {
+	resp, err := client.GetCompaniesWithResponse(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get companies: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+		return nil, fmt.Errorf("got unexpected response: %s", resp.Status())
+	}
+	return resp.JSON200, nil
+}
+
+// resolveCompanyID は、事業所ID・事業所名・事業所名（カナ）のいずれかで指定された事業所を検索し、
+// その事業所IDを返します。
+//
+// 数値のみで構成される場合は事業所IDとみなし、API への問い合わせは行いません。
+func resolveCompanyID(ctx context.Context, client *freeeapi.Client, query string) (int64, error) {
+	if id, err := strconv.ParseInt(query, 10, 64); err == nil {
+		return id, nil
+	}
+	r, err := getCompanies(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	candidates := matchCompanies(r, query)
+	switch len(candidates) {
+	case 0:
+		return 0, fmt.Errorf("事業所が見つかりません: %q", query)
+	case 1:
+		return r.Companies[candidates[0]].Id, nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "事業所を特定できません: %q に該当する事業所が複数あります", query)
+	for _, i := range candidates {
+		c := r.Companies[i]
+		fmt.Fprintf(&b, "\n  %d\t%s", c.Id, deref(c.DisplayName, ""))
+	}
+	b.WriteString("\n事業所IDで指定してください")
+	return 0, fmt.Errorf("%s", b.String())
+}
+
+// matchCompanies は、query に一致する事業所のインデックスを返します。
+//
+// display_name, name, name_kana のいずれかに完全一致する事業所があればそれらを返し、
+// なければ部分一致（大文字・小文字を区別しない）する事業所を返します。
+func matchCompanies(r *freeeapigen.CompanyIndexResponse, query string) []int {
+	var exact, partial []int
+	q := strings.ToLower(query)
+	for i, c := range r.Companies {
+		names := []string{deref(c.DisplayName, ""), deref(c.Name, ""), deref(c.NameKana, "")}
+		var isExact, isPartial bool
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+			if name == query {
+				isExact = true
+			}
+			if strings.Contains(strings.ToLower(name), q) {
+				isPartial = true
+			}
+		}
+		if isExact {
+			exact = append(exact, i)
+		}
+		if isPartial {
+			partial = append(partial, i)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return partial
+}
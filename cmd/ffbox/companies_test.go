@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+func TestMatchCompanies(t *testing.T) {
+	var r freeeapigen.CompanyIndexResponse
+	err := json.Unmarshal([]byte(`{"companies": [
+		{"id": 1, "display_name": "株式会社ABC", "name": "ABC", "name_kana": "エービーシー"},
+		{"id": 2, "display_name": "株式会社ABC商事", "name": "ABC商事", "name_kana": "エービーシーショウジ"},
+		{"id": 3, "display_name": "XYZ合同会社", "name": "XYZ", "name_kana": null}
+	]}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{"exact display name wins over partial matches", "株式会社ABC", []int{0}},
+		{"exact name wins over partial matches", "ABC", []int{0}},
+		{"exact name_kana", "エービーシーショウジ", []int{1}},
+		{"partial match returns all candidates", "株式会社", []int{0, 1}},
+		{"partial match ignores case", "xyz", []int{2}},
+		{"no match", "unknown", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchCompanies(&r, tt.query)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matchCompanies(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"text/tabwriter"
 
 	"github.com/urfave/cli/v3"
 
 	"github.com/micheam/freee-filebox-ctl/internal/config"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
 )
 
 var cmdConfig = []*cli.Command{
@@ -58,15 +60,34 @@ var cmdConfig = []*cli.Command{
 				Name:  "show-file-path",
 				Usage: "設定ファイルのパスを表示します",
 			},
+			&cli.BoolFlag{
+				Name:  "origin",
+				Usage: "各設定値の読み込み元のファイルを表示します",
+			},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			cfg, err := config.Load()
+			cfg, origins, err := config.LoadWithOrigins()
 			if err != nil {
 				return fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
 			}
 
+			if cmd.Bool("origin") {
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				for _, key := range cfg.ResolvedKeys() {
+					v, err := cfg.Get(key)
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(w, "%s\t%#v\t%s\n", key, v, origins.Of(key))
+				}
+				return w.Flush()
+			}
+
 			if cmd.Bool("show-file-path") {
 				fmt.Fprintln(os.Stdout, config.ConfigPath())
+				if path, ok := config.ProjectConfigPath(); ok {
+					fmt.Fprintln(os.Stdout, path)
+				}
 			}
 
 			data, err := cfg.Marshal()
@@ -78,6 +99,120 @@ var cmdConfig = []*cli.Command{
 			return nil
 		},
 	},
+	/* config get */ {
+		Name:      "get",
+		Usage:     "設定値を表示します",
+		ArgsUsage: "<key>",
+		Description: `指定したキーの設定値を表示します。キーはドット区切りで指定します（例: oauth2.local_addr）。
+設定ファイルに記載がない場合は、既定値を表示します。
+
+利用可能なキーは 'ffbox config keys' で確認できます。`,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			key := cmd.Args().First()
+			if key == "" {
+				return fmt.Errorf("キーを指定してください")
+			}
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+			}
+			v, err := cfg.Get(key)
+			if err != nil {
+				return err
+			}
+			fmt.Println(v)
+			return nil
+		},
+	},
+	/* config set */ {
+		Name:      "set",
+		Usage:     "設定値を変更します",
+		ArgsUsage: "<key> <value>",
+		Description: `指定したキーの設定値を変更します。キーはドット区切りで指定します（例: freee.company_id）。
+値は設定項目の型に従って検証されます。設定ファイル内のコメントは保持されます。
+
+利用可能なキーは 'ffbox config keys' で確認できます。`,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() != 2 {
+				return fmt.Errorf("キーと値を指定してください")
+			}
+			key, value := cmd.Args().Get(0), cmd.Args().Get(1)
+			if err := config.Set(key, value); err != nil {
+				return fmt.Errorf("設定値の変更に失敗しました: %w", err)
+			}
+			return nil
+		},
+	},
+	/* config unset */ {
+		Name:      "unset",
+		Usage:     "設定値を削除し、既定値に戻します",
+		ArgsUsage: "<key>",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			key := cmd.Args().First()
+			if key == "" {
+				return fmt.Errorf("キーを指定してください")
+			}
+			if err := config.Unset(key); err != nil {
+				return fmt.Errorf("設定値の削除に失敗しました: %w", err)
+			}
+			return nil
+		},
+	},
+	/* config keys */ {
+		Name:  "keys",
+		Usage: "設定可能なキーの一覧を表示します",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			for _, key := range config.Keys() {
+				fmt.Println(key)
+			}
+			return nil
+		},
+	},
+	/* config validate */ {
+		Name:  "validate",
+		Usage: "設定ファイルを検証し、不明なキーや不正な値を報告します",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			configPath := config.ConfigPath()
+			data, err := os.ReadFile(configPath)
+			if err != nil {
+				return fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+			}
+			problems := config.Validate(data)
+			if len(problems) == 0 {
+				fmt.Printf("設定ファイルに問題は見つかりませんでした: %q\n", configPath)
+				return nil
+			}
+			for _, p := range problems {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", configPath, p)
+			}
+			return fmt.Errorf("設定ファイルに %d 件の問題が見つかりました", len(problems))
+		},
+	},
+	/* config profiles */ {
+		Name:  "profiles",
+		Usage: "設定ファイルに定義されたプロファイルの一覧を表示します",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+			}
+			names := cfg.ProfileNames()
+			if len(names) == 0 {
+				fmt.Fprintln(os.Stderr, "プロファイルが定義されていません")
+				return nil
+			}
+			selected := cmd.String(flagProfile.Name)
+			for _, name := range names {
+				mark := " "
+				if name == selected {
+					mark = "*"
+				}
+				p := cfg.Profiles[name]
+				fmt.Printf("%s %s\tcompany_id=%d\ttoken_file=%s\n", mark, name, p.CompanyID, p.TokenFileOrDefault(name))
+			}
+			return nil
+		},
+	},
 }
 
 // -----------------------------------------------------------------------------
@@ -85,12 +220,20 @@ var cmdConfig = []*cli.Command{
 // -----------------------------------------------------------------------------
 
 // loadAppConfig は、コマンド実行前に設定ファイルを読み込み、コンテキストに設定を注入します。
-func loadAppConfig(ctx context.Context, _ *cli.Command) (context.Context, error) {
+//
+// 設定ファイルが存在しない場合は、既定の設定を使用します。
+// 設定ファイルの読み込みや解析に失敗した場合は、エラーを返します。
+//
+// --profile が指定された場合は、該当するプロファイルの設定を適用します。
+func loadAppConfig(ctx context.Context, cmd *cli.Command) (context.Context, error) {
 	cfg, err := config.Load()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "警告: 設定の読み込みに失敗しました: %v\n", err)
-		fmt.Fprintf(os.Stderr, "デフォルト設定を使用します\n")
-		cfg = config.Default()
+		return ctx, fmt.Errorf("設定の読み込みに失敗しました: %w", err)
+	}
+	if name := cmd.String(flagProfile.Name); name != "" {
+		if err := cfg.UseProfile(name); err != nil {
+			return ctx, fmt.Errorf("プロファイルの適用に失敗しました: %w", err)
+		}
 	}
 	ctx = config.NewContext(ctx, cfg)
 	return ctx, nil
@@ -98,6 +241,16 @@ func loadAppConfig(ctx context.Context, _ *cli.Command) (context.Context, error)
 
 var _ cli.BeforeFunc = loadAppConfig
 
+// setupConfigPath は、--config フラグ（環境変数 FFBOX_CONFIG）で指定された設定ファイルのパスを設定します。
+func setupConfigPath(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	if err := config.SetConfigPath(cmd.String(flagConfig.Name)); err != nil {
+		return ctx, err
+	}
+	return ctx, nil
+}
+
+var _ cli.BeforeFunc = setupConfigPath
+
 // selectEditor は、使用するエディタを環境変数に基づいて決定します。
 // 一般的なUnixの慣習に従い、以下の優先順位で選択します:
 //
@@ -118,18 +271,19 @@ func selectEditor() string {
 //  2. 環境変数
 //  3. 設定ファイル
 //
+// コマンドライン引数・環境変数では、事業所IDのほか事業所名・事業所名（カナ）も指定できます。
+// 事業所名で指定された場合は、client を使用して事業所IDを解決します。
+//
 // 全ての方法で事業所IDが見つからなかった場合、エラーを返します。
-func detectCompanyID(ctx context.Context, cmd *cli.Command) (int64, error) {
+func detectCompanyID(ctx context.Context, cmd *cli.Command, client *freeeapi.Client) (int64, error) {
 	// 1. コマンドライン引数
 	// 2. 環境変数
 	if cmd.IsSet(flagCompanyID.Name) {
-		rawCompanyID := cmd.String(flagCompanyID.Name)
-		var parsed int64
-		_, err := fmt.Sscanf(rawCompanyID, "%d", &parsed)
+		companyID, err := resolveCompanyID(ctx, client, cmd.String(flagCompanyID.Name))
 		if err != nil {
-			return 0, fmt.Errorf("invalid company-id: %w", err)
+			return 0, fmt.Errorf("invalid company: %w", err)
 		}
-		return parsed, nil
+		return companyID, nil
 	}
 	// 3. 設定ファイル
 	cfg := config.FromContext(ctx)
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/audit"
+	"github.com/micheam/freee-filebox-ctl/internal/config"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+var cmdDeal = &cli.Command{
+	Name:   "deal",
+	Usage:  "取引を操作します",
+	Before: loadAppConfig,
+	Commands: []*cli.Command{
+		cmdDealCreate,
+	},
+}
+
+var (
+	flagDealCreateFromReceipt = &cli.Int64Flag{
+		Name:     "from-receipt",
+		Usage:    "取引の作成元とする証憑ID",
+		Required: true,
+	}
+	flagDealCreateAccountItemID = &cli.Int64Flag{
+		Name:  "account-item-id",
+		Usage: "勘定科目ID（省略時は設定ファイルの deal.account_item_id）",
+	}
+	flagDealCreateTaxCode = &cli.Int64Flag{
+		Name:  "tax-code",
+		Usage: "税区分コード（省略時は設定ファイルの deal.tax_code）",
+	}
+	flagDealCreateType = &cli.StringFlag{
+		Name:  "type",
+		Usage: "収支区分（expense、income）",
+		Value: "expense",
+		Validator: func(in string) error {
+			switch in {
+			case "expense", "income":
+				return nil
+			default:
+				return fmt.Errorf("収支区分が不正です: %s", in)
+			}
+		},
+	}
+	flagDealCreatePartnerID = &cli.Int64Flag{
+		Name:  "partner-id",
+		Usage: "取引先ID（省略時は証憑の発行元の名前から検索）",
+	}
+	flagDealCreateDescription = &cli.StringFlag{
+		Name:  "description",
+		Usage: "取引明細の備考（省略時は証憑のメモ）",
+	}
+	flagDealCreateDryRun = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "取引を作成せず、送信する内容を JSON で表示します",
+	}
+)
+
+var cmdDealCreate = &cli.Command{
+	Name:      "create",
+	Usage:     "証憑ファイルから取引を作成します",
+	ArgsUsage: "--from-receipt <receipt-id>",
+	Description: `証憑ファイルの発行日・金額・発行元をもとに取引（未決済）を作成し、その証憑を添付します。
+
+発行元の名前と一致する取引先を freee から検索し、取引先として設定します。
+勘定科目と税区分は、フラグまたは設定ファイルの [deal] セクションで指定します。
+
+  ffbox config set deal.account_item_id 101
+  ffbox config set deal.tax_code 136
+  ffbox deal create --from-receipt 999999999`,
+	Flags: []cli.Flag{
+		flagDealCreateFromReceipt,
+		flagDealCreateAccountItemID,
+		flagDealCreateTaxCode,
+		flagDealCreateType,
+		flagDealCreatePartnerID,
+		flagDealCreateDescription,
+		flagDealCreateDryRun,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		opts, err := newDealOptions(ctx, cmd.Int64(flagDealCreateAccountItemID.Name), cmd.Int64(flagDealCreateTaxCode.Name))
+		if err != nil {
+			return err
+		}
+		opts.dealType = cmd.String(flagDealCreateType.Name)
+		opts.partnerID = cmd.Int64(flagDealCreatePartnerID.Name)
+		opts.description = cmd.String(flagDealCreateDescription.Name)
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+
+		receiptID := cmd.Int64(flagDealCreateFromReceipt.Name)
+		receipt, err := getReceipt(ctx, freeeapiClient, companyID, receiptID)
+		if err != nil {
+			return err
+		}
+		params, err := newDealCreateParams(ctx, freeeapiClient, companyID, receipt, opts)
+		if err != nil {
+			return err
+		}
+
+		if cmd.Bool(flagDealCreateDryRun.Name) {
+			b, err := json.MarshalIndent(params, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshal deal params: %w", err)
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		id, err := createDeal(ctx, freeeapiClient, *params)
+		if err != nil {
+			return err
+		}
+		fmt.Println(id)
+		return nil
+	},
+}
+
+// dealOptions は、証憑から取引を作成するときの指定です。
+type dealOptions struct {
+	accountItemID int64
+	taxCode       int64
+	// dealType は収支区分（expense、income）です。空の場合は expense とします。
+	dealType string
+	// partnerID は取引先IDです。0 の場合は証憑の発行元の名前から検索します。
+	partnerID int64
+	// description は取引明細の備考です。空の場合は証憑のメモを使用します。
+	description string
+}
+
+// newDealOptions は、フラグで指定された勘定科目と税区分から dealOptions を作成します。
+// フラグが 0 の場合は、設定ファイルの [deal] セクションの値を使用します。
+func newDealOptions(ctx context.Context, accountItemID, taxCode int64) (dealOptions, error) {
+	cfg := config.FromContext(ctx)
+	opts := dealOptions{
+		accountItemID: cmp.Or(accountItemID, cfg.Deal.AccountItemID),
+		taxCode:       cmp.Or(taxCode, cfg.Deal.TaxCode),
+	}
+	if opts.accountItemID == 0 {
+		return opts, fmt.Errorf("勘定科目が指定されていません: --account-item-id または設定ファイルの deal.account_item_id を指定してください")
+	}
+	if opts.taxCode == 0 {
+		return opts, fmt.Errorf("税区分が指定されていません: --tax-code または設定ファイルの deal.tax_code を指定してください")
+	}
+	return opts, nil
+}
+
+// newDealCreateParams は、証憑の発行日・金額・発行元から、その証憑を添付した取引の作成内容を返します。
+func newDealCreateParams(ctx context.Context, client *freeeapi.Client, companyID int64, receipt *freeeapigen.Receipt, opts dealOptions) (*freeeapigen.DealCreateParams, error) {
+	var amount *int64
+	var issueDate, partnerName *string
+	if m := receipt.ReceiptMetadatum; m != nil {
+		amount, issueDate, partnerName = m.Amount, m.IssueDate, m.PartnerName
+	}
+	if issueDate == nil || *issueDate == "" {
+		return nil, fmt.Errorf("証憑 %d に発行日が設定されていません", receipt.Id)
+	}
+	if amount == nil {
+		return nil, fmt.Errorf("証憑 %d に金額が設定されていません", receipt.Id)
+	}
+
+	partnerID := opts.partnerID
+	if partnerID == 0 && partnerName != nil && *partnerName != "" {
+		id, found, err := findPartnerID(ctx, client, companyID, *partnerName)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			partnerID = id
+		} else {
+			fmt.Fprintf(os.Stderr, "取引先 %q が見つからないため、取引先を設定せずに作成します\n", *partnerName)
+		}
+	}
+
+	params := &freeeapigen.DealCreateParams{
+		CompanyId:  companyID,
+		IssueDate:  *issueDate,
+		Type:       freeeapigen.DealCreateParamsType(cmp.Or(opts.dealType, "expense")),
+		ReceiptIds: &[]int64{receipt.Id},
+	}
+	if partnerID != 0 {
+		params.PartnerId = &partnerID
+	}
+	// Details is a slice of an anonymous struct, so grow it instead of appending a literal.
+	params.Details = slices.Grow(params.Details, 1)[:1]
+	detail := &params.Details[0]
+	detail.AccountItemId = &opts.accountItemID
+	detail.TaxCode = opts.taxCode
+	detail.Amount = *amount
+	if opts.description != "" {
+		detail.Description = &opts.description
+	} else if receipt.Description != nil && *receipt.Description != "" {
+		detail.Description = receipt.Description
+	}
+	return params, nil
+}
+
+// createDeal は、取引を作成し、作成した取引のIDを返します。
+func createDeal(ctx context.Context, client *freeeapi.Client, params freeeapigen.DealCreateParams) (int64, error) {
+	resp, err := client.CreateDealWithResponse(ctx, params)
+	if err != nil {
+		return 0, fmt.Errorf("create deal: %w", err)
+	}
+	if resp.StatusCode() != http.StatusCreated || resp.JSON201 == nil {
+		return 0, fmt.Errorf("got unexpected response: %s", resp.Status())
+	}
+	id := resp.JSON201.Deal.Id
+	invalidateReceiptLinks(params.CompanyId)
+	entry := audit.Entry{
+		CompanyID:  params.CompanyId,
+		ReceiptIDs: deref(params.ReceiptIds, nil),
+		Operation:  auditOpDealCreate,
+		Target:     fmt.Sprintf("%s:%d", linkTypeDeal, id),
+	}
+	if err := recordAudit(ctx, entry, params); err != nil {
+		return 0, fmt.Errorf("取引 %d: %w", id, err)
+	}
+	return id, nil
+}
+
+// getReceipt は、指定した証憑を取得します。
+func getReceipt(ctx context.Context, client *freeeapi.Client, companyID, receiptID int64) (*freeeapigen.Receipt, error) {
+	resp, err := client.GetReceiptWithResponse(ctx, receiptID, &freeeapigen.GetReceiptParams{CompanyId: companyID})
+	if err != nil {
+		return nil, fmt.Errorf("get receipt ID %d: %w", receiptID, err)
+	}
+	if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+		return nil, fmt.Errorf("got unexpected response for receipt ID %d: %s", receiptID, resp.Status())
+	}
+	return &resp.JSON200.Receipt, nil
+}
+
+// findPartnerID は、名前が name と一致する取引先を検索し、その取引先IDを返します。
+//
+// 取引先名または正式名称に完全一致する取引先を対象とします。
+// 該当する取引先がない場合は found に false を返し、複数ある場合はエラーを返します。
+func findPartnerID(ctx context.Context, client *freeeapi.Client, companyID int64, name string) (id int64, found bool, err error) {
+	resp, err := client.GetPartnersWithResponse(ctx, &freeeapigen.GetPartnersParams{
+		CompanyId: companyID,
+		Keyword:   &name,
+		Limit:     ptr(int64(3000)),
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("get partners: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+		return 0, false, fmt.Errorf("got unexpected response: %s", resp.Status())
+	}
+	var candidates []int64
+	for _, p := range resp.JSON200.Partners {
+		if p.Name == name || deref(p.LongName, "") == name {
+			candidates = append(candidates, p.Id)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return 0, false, nil
+	case 1:
+		return candidates[0], true, nil
+	}
+	s := make([]string, len(candidates))
+	for i, id := range candidates {
+		s[i] = fmt.Sprint(id)
+	}
+	return 0, false, fmt.Errorf("取引先 %q に該当する取引先が複数あります（%s）: --partner-id で指定してください", name, strings.Join(s, ", "))
+}
@@ -0,0 +1,1577 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/oauth2"
+
+	"github.com/micheam/freee-filebox-ctl/internal/audit"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi/fake"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// testToken is the access token that the fake server accepts in E2E tests.
+const testToken = "test-token"
+
+// pristineApp is a copy of app taken before any test runs it.
+// urfave/cli keeps parsed state in commands and flags, so every run starts from a fresh copy of it.
+var pristineApp = cloneCommand(app)
+
+// cloneCommand returns a copy of cmd and its subcommands with copies of their flags.
+func cloneCommand(cmd *cli.Command) *cli.Command {
+	c := reflect.New(reflect.TypeOf(cmd).Elem())
+	c.Elem().Set(reflect.ValueOf(cmd).Elem())
+	clone := c.Interface().(*cli.Command)
+	clone.Flags = make([]cli.Flag, len(cmd.Flags))
+	for i, f := range cmd.Flags {
+		v := reflect.New(reflect.TypeOf(f).Elem())
+		v.Elem().Set(reflect.ValueOf(f).Elem())
+		clone.Flags[i] = v.Interface().(cli.Flag)
+	}
+	clone.Commands = make([]*cli.Command, len(cmd.Commands))
+	for i, sub := range cmd.Commands {
+		clone.Commands[i] = cloneCommand(sub)
+	}
+	return clone
+}
+
+// e2e runs ffbox against a fake freee API server.
+type e2e struct {
+	t   *testing.T
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	requests []*capturedRequest
+}
+
+// capturedRequest is a request received by the fake server.
+type capturedRequest struct {
+	Method string
+	Path   string
+	Query  map[string][]string
+	Body   []byte
+	// Fields and Files hold the multipart/form-data parts, if any.
+	Fields map[string]string
+	Files  map[string]capturedFile
+}
+
+type capturedFile struct {
+	Filename string
+	Content  []byte
+}
+
+// newE2E starts handler as a fake freee API server and isolates the config
+// files and environment variables from the user's ones.
+func newE2E(t *testing.T, handler http.Handler) *e2e {
+	t.Helper()
+	e := &e2e{t: t}
+	e.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.capture(r)
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(e.srv.Close)
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	for _, name := range []string{
+		"FREEEAPI_OAUTH2_CLIENT_ID", "FREEEAPI_OAUTH2_CLIENT_SECRET", "FREEEAPI_COMPANY_ID",
+		"FFBOX_ACCESS_TOKEN", "FFBOX_PROFILE", "FFBOX_CONFIG",
+		"FFBOX_OAUTH2_AUTH_URL", "FFBOX_OAUTH2_TOKEN_URL",
+	} {
+		t.Setenv(name, "") // restored on cleanup
+		os.Unsetenv(name)
+	}
+	t.Setenv("FFBOX_API_ENDPOINT", e.srv.URL+"/")
+	return e
+}
+
+// capture records r, restoring its body for the handler.
+func (e *e2e) capture(r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		e.t.Errorf("read request body: %v", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	req := &capturedRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Body:   body,
+		Fields: map[string]string{},
+		Files:  map[string]capturedFile{},
+	}
+	if mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "multipart/form-data" {
+		mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			content, _ := io.ReadAll(p)
+			if p.FileName() != "" {
+				req.Files[p.FormName()] = capturedFile{Filename: p.FileName(), Content: content}
+			} else {
+				req.Fields[p.FormName()] = string(content)
+			}
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = append(e.requests, req)
+}
+
+// lastRequest returns the last request that matches method and path.
+func (e *e2e) lastRequest(method, path string) *capturedRequest {
+	e.t.Helper()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := len(e.requests) - 1; i >= 0; i-- {
+		if r := e.requests[i]; r.Method == method && r.Path == path {
+			return r
+		}
+	}
+	e.t.Fatalf("no request: %s %s", method, path)
+	return nil
+}
+
+// requestCount returns the number of requests that match method and path.
+func (e *e2e) requestCount(method, path string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var n int
+	for _, r := range e.requests {
+		if r.Method == method && r.Path == path {
+			n++
+		}
+	}
+	return n
+}
+
+// run runs ffbox with args and returns what it wrote to stdout.
+// Global flags are given before the command name as usual, e.g. run("--company", "1", "list").
+func (e *e2e) run(args ...string) (string, error) {
+	e.t.Helper()
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, e.srv.Client())
+
+	out, err := os.CreateTemp(e.t.TempDir(), "stdout")
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	cmd := cloneCommand(pristineApp)
+	cmd.ErrWriter = io.Discard
+	args = append([]string{"ffbox", "--access-token", testToken}, args...)
+	runErr := cmd.Run(ctx, args)
+
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return string(b), runErr
+}
+
+// newFakeServer returns a fake server with a company (ID 1) and a receipt (ID 1001).
+func newFakeServer() *fake.Server {
+	srv := fake.New()
+	srv.Token = testToken
+	srv.AddCompany(fake.Company{ID: 1, Name: "株式会社テスト", DisplayName: "株式会社テスト", CompanyNumber: "1111111111"})
+	srv.AddCompany(fake.Company{ID: 2, Name: "テスト商店", DisplayName: "テスト商店", CompanyNumber: "2222222222"})
+	srv.AddReceipt(1, freeeapigen.Receipt{
+		CreatedAt:        "2025-04-02T10:00:00+09:00",
+		Status:           freeeapigen.ReceiptStatusConfirmed,
+		Origin:           freeeapigen.PublicApi,
+		MimeType:         "application/pdf",
+		DocumentType:     ptr(freeeapigen.ReceiptDocumentType("receipt")),
+		ReceiptMetadatum: receiptMetadatum(1100, "2025-04-01", "テスト文具店"),
+	}, "receipt.pdf", []byte("%PDF-1.4\n"))
+	return srv
+}
+
+// receiptMetadatum returns the metadatum of a receipt. An empty partner leaves
+// the partner name unset.
+func receiptMetadatum(amount int64, issueDate, partner string) *struct {
+	Amount      *int64  `json:"amount"`
+	IssueDate   *string `json:"issue_date"`
+	PartnerName *string `json:"partner_name"`
+} {
+	m := &struct {
+		Amount      *int64  `json:"amount"`
+		IssueDate   *string `json:"issue_date"`
+		PartnerName *string `json:"partner_name"`
+	}{Amount: &amount, IssueDate: &issueDate}
+	if partner != "" {
+		m.PartnerName = &partner
+	}
+	return m
+}
+
+func TestE2EList(t *testing.T) {
+	e := newE2E(t, newFakeServer())
+
+	out, err := e.run("--company", "1", "list", "--created-start", "2025-04-01", "--created-end", "2025-04-30", "--format", "json", "--fields", "id,amount,partner_name")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if want := `{"amount":1100,"id":1001,"partner_name":"テスト文具店"}` + "\n"; out != want {
+		t.Errorf("list output = %q, want %q", out, want)
+	}
+	req := e.lastRequest(http.MethodGet, "/api/1/receipts")
+	for key, want := range map[string]string{"company_id": "1", "start_date": "2025-04-01", "end_date": "2025-04-30", "limit": "50"} {
+		if got := strings.Join(req.Query[key], ","); got != want {
+			t.Errorf("query %s = %q, want %q", key, got, want)
+		}
+	}
+
+	// The company is resolved by name through /api/1/companies.
+	out, err = e.run("--company", "テスト商店", "list", "--created-start", "2025-04-01", "--created-end", "2025-04-30", "--format", "json")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if out != "" {
+		t.Errorf("list output for company 2 = %q, want empty", out)
+	}
+	if got := e.lastRequest(http.MethodGet, "/api/1/receipts").Query["company_id"]; len(got) != 1 || got[0] != "2" {
+		t.Errorf("company_id = %v, want [2]", got)
+	}
+
+	out, err = e.run("--company", "1", "list", "--created-start", "2025-04-01", "--created-end", "2025-04-30")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	for _, want := range []string{"1001", "テスト文具店", "¥1,100"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("list table does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestE2EShow(t *testing.T) {
+	e := newE2E(t, newFakeServer())
+
+	out, err := e.run("--company", "1", "show", "--format", "json", "1001")
+	if err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	var got freeeapigen.Receipt
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal show output: %v\n%s", err, out)
+	}
+	if got.Id != 1001 || got.ReceiptMetadatum == nil || deref(got.ReceiptMetadatum.PartnerName, "") != "テスト文具店" {
+		t.Errorf("show output = %s", out)
+	}
+	if got := e.lastRequest(http.MethodGet, "/api/1/receipts/1001").Query["company_id"]; len(got) != 1 || got[0] != "1" {
+		t.Errorf("company_id = %v, want [1]", got)
+	}
+
+	out, err = e.run("--company", "1", "show", "1001")
+	if err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	if !strings.Contains(out, "ID:              1001") {
+		t.Errorf("show output does not contain the ID:\n%s", out)
+	}
+}
+
+func TestE2EUpload(t *testing.T) {
+	srv := newFakeServer()
+	e := newE2E(t, srv)
+	path := filepath.Join(t.TempDir(), "invoice.pdf")
+	content := []byte("%PDF-1.4\ninvoice\n")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := e.run("--company", "1", "upload",
+		"--description", "4月分",
+		"--document-type", "invoice",
+		"--qualified-invoice", "qualified",
+		"--amount", "5500",
+		"--issue-date", "2025-04-30",
+		"--partner-name", "株式会社取引先",
+		path)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if out != "1002\n" {
+		t.Errorf("upload output = %q, want %q", out, "1002\n")
+	}
+
+	req := e.lastRequest(http.MethodPost, "/api/1/receipts")
+	wantFields := map[string]string{
+		"company_id":                     "1",
+		"description":                    "4月分",
+		"document_type":                  "invoice",
+		"qualified_invoice":              "qualified",
+		"receipt_metadatum_amount":       "5500",
+		"receipt_metadatum_issue_date":   "2025-04-30",
+		"receipt_metadatum_partner_name": "株式会社取引先",
+	}
+	if !reflect.DeepEqual(req.Fields, wantFields) {
+		t.Errorf("multipart fields = %v, want %v", req.Fields, wantFields)
+	}
+	file, ok := req.Files["receipt"]
+	if !ok {
+		t.Fatalf("multipart file %q is missing: %v", "receipt", req.Files)
+	}
+	if file.Filename != "invoice.pdf" || !bytes.Equal(file.Content, content) {
+		t.Errorf("multipart file = %q (%q), want %q (%q)", file.Filename, file.Content, "invoice.pdf", content)
+	}
+
+	created, stored, ok := srv.Receipt(1002)
+	if !ok {
+		t.Fatal("uploaded receipt is not stored")
+	}
+	if created.MimeType != "application/pdf" || !bytes.Equal(stored, content) {
+		t.Errorf("stored receipt = %s %q", created.MimeType, stored)
+	}
+
+	// Without optional flags, only the required fields are sent.
+	if _, err := e.run("--company", "1", "upload", "--filename", "renamed.pdf", path); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	req = e.lastRequest(http.MethodPost, "/api/1/receipts")
+	if want := map[string]string{"company_id": "1"}; !reflect.DeepEqual(req.Fields, want) {
+		t.Errorf("multipart fields = %v, want %v", req.Fields, want)
+	}
+	if got := req.Files["receipt"].Filename; got != "renamed.pdf" {
+		t.Errorf("filename = %q, want %q", got, "renamed.pdf")
+	}
+
+	// A description longer than 255 characters is rejected before uploading.
+	uploads := e.requestCount(http.MethodPost, "/api/1/receipts")
+	if _, err := e.run("--company", "1", "upload", "--description", strings.Repeat("あ", 256), path); err == nil {
+		t.Error("upload with a too long description: error = nil, want error")
+	}
+	if n := e.requestCount(http.MethodPost, "/api/1/receipts"); n != uploads {
+		t.Errorf("upload with a too long description sent %d requests", n-uploads)
+	}
+}
+
+func TestE2EErrors(t *testing.T) {
+	failUpload := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"status_code":500,"errors":[{"type":"status","messages":["internal error"]}]}`)
+	})
+	srv := newFakeServer()
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/1/receipts", failUpload)
+	mux.Handle("/", srv)
+
+	path := filepath.Join(t.TempDir(), "receipt.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "show unknown receipt",
+			handler: srv,
+			args:    []string{"--company", "1", "show", "9999"},
+			wantErr: "404",
+		},
+		{
+			name:    "list for unknown company",
+			handler: srv,
+			args:    []string{"--company", "99", "list"},
+			wantErr: "404",
+		},
+		{
+			name:    "unknown company name",
+			handler: srv,
+			args:    []string{"--company", "存在しない", "list"},
+			wantErr: "事業所が見つかりません",
+		},
+		{
+			name: "invalid token",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.Header.Set("Authorization", "Bearer invalid")
+				srv.ServeHTTP(w, r)
+			}),
+			args:    []string{"--company", "1", "list"},
+			wantErr: "401",
+		},
+		{
+			name:    "upload server error",
+			handler: mux,
+			args:    []string{"--company", "1", "upload", path},
+			wantErr: "500",
+		},
+		{
+			name:    "no company",
+			handler: srv,
+			args:    []string{"list"},
+			wantErr: "事業者IDが指定されていません",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newE2E(t, tt.handler)
+			_, err := e.run(tt.args...)
+			if err == nil {
+				t.Fatalf("run(%q) error = nil, want %q", tt.args, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("run(%q) error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestE2ERecordAndReplay(t *testing.T) {
+	e := newE2E(t, newFakeServer())
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorded, err := e.run("--company", "1", "--record", cassette, "show", "--format", "json", "1001")
+	if err != nil {
+		t.Fatalf("show --record: %v", err)
+	}
+	e.srv.Close() // replay must not access the network
+
+	replayed, err := e.run("--company", "1", "--replay", cassette, "show", "--format", "json", "1001")
+	if err != nil {
+		t.Fatalf("show --replay: %v", err)
+	}
+	if replayed != recorded {
+		t.Errorf("replayed output = %q, want %q", replayed, recorded)
+	}
+
+	if _, err := e.run("--company", "1", "--replay", cassette, "show", "1002"); err == nil {
+		t.Error("show --replay for an unrecorded request: error = nil, want error")
+	}
+	if _, err := e.run("--record", cassette, "--replay", cassette, "list"); err == nil {
+		t.Error("--record with --replay: error = nil, want error")
+	}
+}
+
+func TestE2EAttachAndDetach(t *testing.T) {
+	srv := newFakeServer()
+	var d freeeapigen.Deal
+	err := json.Unmarshal([]byte(`{
+		"issue_date": "2025-04-01", "type": "expense", "partner_id": 10, "ref_number": "A-1", "status": "unsettled",
+		"details": [{"id": 1, "account_item_id": 100, "tax_code": 136, "amount": 1100, "vat": 100, "entry_side": "debit", "description": "文具"}]
+	}`), &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiptID := srv.AddReceipt(1, freeeapigen.Receipt{CreatedAt: "2025-04-03T10:00:00+09:00", Status: freeeapigen.ReceiptStatusConfirmed}, "r.pdf", nil)
+	dealID := srv.AddDeal(1, d, 1001)
+	e := newE2E(t, srv)
+	deal := fmt.Sprint(dealID)
+
+	out, err := e.run("--company", "1", "attach", "--deal", deal, fmt.Sprint(receiptID), "1001")
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if want := fmt.Sprintf("取引 %d の証憑: 1001, %d\n", dealID, receiptID); out != want {
+		t.Errorf("attach output = %q, want %q", out, want)
+	}
+	got, _ := srv.Deal(dealID)
+	if got.IssueDate != "2025-04-01" || deref(got.RefNumber, "") != "A-1" || deref(got.PartnerId, 0) != 10 {
+		t.Errorf("deal fields are not kept: %+v", got)
+	}
+	if got.Details == nil || len(*got.Details) != 1 || (*got.Details)[0].Id != 1 || (*got.Details)[0].Amount != 1100 {
+		t.Errorf("deal details are not kept: %+v", got.Details)
+	}
+
+	if _, err := e.run("--company", "1", "detach", "--deal", deal, "1001"); err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+	got, _ = srv.Deal(dealID)
+	if ids := freeeapi.DealReceiptIDs(&got); !reflect.DeepEqual(ids, []int64{receiptID}) {
+		t.Errorf("receipts after detach = %v, want [%d]", ids, receiptID)
+	}
+
+	if _, err := e.run("--company", "1", "attach", "--deal", deal, "9999"); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("attach unknown receipt: error = %v, want 400", err)
+	}
+	if _, err := e.run("--company", "1", "attach", "1001"); err == nil {
+		t.Error("attach without --deal: error = nil, want error")
+	}
+}
+
+func TestE2EDealCreate(t *testing.T) {
+	srv := newFakeServer()
+	srv.AddPartner(fake.Partner{ID: 10, CompanyID: 1, Name: "テスト文具店"})
+	srv.AddPartner(fake.Partner{ID: 11, CompanyID: 1, Name: "テスト文具店 本店"})
+	e := newE2E(t, srv)
+
+	if _, err := e.run("--company", "1", "deal", "create", "--from-receipt", "1001"); err == nil || !strings.Contains(err.Error(), "勘定科目") {
+		t.Errorf("deal create without account item: error = %v, want error", err)
+	}
+
+	if _, err := e.run("config", "set", "deal.tax_code", "136"); err != nil {
+		t.Fatalf("config set: %v", err)
+	}
+	out, err := e.run("--company", "1", "deal", "create", "--from-receipt", "1001", "--account-item-id", "100")
+	if err != nil {
+		t.Fatalf("deal create: %v", err)
+	}
+	var dealID int64
+	if _, err := fmt.Sscan(out, &dealID); err != nil {
+		t.Fatalf("deal create output = %q, want deal ID", out)
+	}
+	got, ok := srv.Deal(dealID)
+	if !ok {
+		t.Fatalf("deal %d is not created", dealID)
+	}
+	if got.IssueDate != "2025-04-01" || deref(got.PartnerId, 0) != 10 || got.Amount != 1100 {
+		t.Errorf("created deal = %+v", got)
+	}
+	if got.Details == nil || len(*got.Details) != 1 || (*got.Details)[0].AccountItemId != 100 || (*got.Details)[0].TaxCode != 136 {
+		t.Errorf("created deal details = %+v", got.Details)
+	}
+	if ids := freeeapi.DealReceiptIDs(&got); !reflect.DeepEqual(ids, []int64{1001}) {
+		t.Errorf("receipts of created deal = %v, want [1001]", ids)
+	}
+}
+
+func TestE2EManualJournals(t *testing.T) {
+	srv := newFakeServer()
+	var j freeeapigen.ManualJournal
+	err := json.Unmarshal([]byte(`{
+		"issue_date": "2026-03-31", "adjustment": true,
+		"details": [
+			{"id": 1, "entry_side": "debit", "account_item_id": 100, "tax_code": 0, "amount": 1100, "description": "前払費用"},
+			{"id": 2, "entry_side": "credit", "account_item_id": 200, "tax_code": 0, "amount": 1100}
+		]
+	}`), &j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journalID := srv.AddManualJournal(1, j)
+	e := newE2E(t, srv)
+	journal := fmt.Sprint(journalID)
+
+	out, err := e.run("--company", "1", "attach", "--manual-journal", journal, "1001")
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if want := fmt.Sprintf("振替伝票 %d の証憑: 1001\n", journalID); out != want {
+		t.Errorf("attach output = %q, want %q", out, want)
+	}
+	got, _ := srv.ManualJournal(journalID)
+	if !got.Adjustment || len(got.Details) != 2 || got.Details[0].Id != 1 || got.Details[1].Amount != 1100 {
+		t.Errorf("manual journal fields are not kept: %+v", got)
+	}
+
+	// The default window is from a month before to a year after the issue date of the receipt.
+	out, err = e.run("--company", "1", "manual-journals", "1001")
+	if err != nil {
+		t.Fatalf("manual-journals: %v", err)
+	}
+	if !strings.Contains(out, journal+"  2026-03-31  1100    yes") {
+		t.Errorf("manual-journals output = %q, want the journal %s", out, journal)
+	}
+	out, err = e.run("--company", "1", "manual-journals", "--end-issue-date", "2025-12-31", "1001")
+	if err != nil {
+		t.Fatalf("manual-journals: %v", err)
+	}
+	if strings.Contains(out, journal) {
+		t.Errorf("manual-journals output = %q, want no journals", out)
+	}
+
+	if _, err := e.run("--company", "1", "detach", "--manual-journal", journal, "1001"); err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+	got, _ = srv.ManualJournal(journalID)
+	if ids := freeeapi.ManualJournalReceiptIDs(&got); len(ids) != 0 {
+		t.Errorf("receipts after detach = %v, want none", ids)
+	}
+	if _, err := e.run("--company", "1", "attach", "--deal", "1", "--manual-journal", journal, "1001"); err == nil {
+		t.Error("attach with --deal and --manual-journal: error = nil, want error")
+	}
+}
+
+func TestE2EExpenseCreate(t *testing.T) {
+	srv := newFakeServer()
+	receiptID := srv.AddReceipt(1, freeeapigen.Receipt{
+		CreatedAt:        "2025-04-06T10:00:00+09:00",
+		Status:           freeeapigen.ReceiptStatusConfirmed,
+		Description:      ptr("タクシー代"),
+		ReceiptMetadatum: receiptMetadatum(550, "2025-04-05", ""),
+	}, "taxi.jpg", nil)
+	e := newE2E(t, srv)
+
+	out, err := e.run("--company", "1", "expense", "create", "--title", "4月分", "--line-template-id", "7", "1001", fmt.Sprint(receiptID))
+	if err != nil {
+		t.Fatalf("expense create: %v", err)
+	}
+	var id int64
+	if _, err := fmt.Sscan(out, &id); err != nil {
+		t.Fatalf("expense create output = %q, want expense application ID", out)
+	}
+	got, ok := srv.ExpenseApplication(id)
+	if !ok {
+		t.Fatalf("expense application %d is not created", id)
+	}
+	a := got.ExpenseApplication
+	if a.Title != "4月分" || a.Status != freeeapigen.ExpenseApplicationResponseExpenseApplicationStatusDraft || deref(a.TotalAmount, 0) != 1650 {
+		t.Errorf("created expense application = %+v", a)
+	}
+	if a.PurchaseLines == nil || len(*a.PurchaseLines) != 2 {
+		t.Fatalf("purchase lines = %+v, want 2 lines", a.PurchaseLines)
+	}
+	for i, want := range []struct {
+		receiptID   int64
+		date, descr string
+	}{{1001, "2025-04-01", "テスト文具店"}, {receiptID, "2025-04-05", "タクシー代"}} {
+		line := (*a.PurchaseLines)[i]
+		if deref(line.ReceiptId, 0) != want.receiptID || deref(line.TransactionDate, "") != want.date {
+			t.Errorf("purchase line[%d] = %+v, want receipt %d on %s", i, line, want.receiptID, want.date)
+		}
+		if line.ExpenseApplicationLines == nil || len(*line.ExpenseApplicationLines) != 1 {
+			t.Errorf("purchase line[%d] has no expense line", i)
+			continue
+		}
+		l := (*line.ExpenseApplicationLines)[0]
+		if deref(l.Description, "") != want.descr || deref(l.ExpenseApplicationLineTemplateId, 0) != 7 {
+			t.Errorf("expense line[%d] = %+v, want %q with template 7", i, l, want.descr)
+		}
+	}
+
+	noAmount := srv.AddReceipt(1, freeeapigen.Receipt{CreatedAt: "2025-04-06T10:00:00+09:00", Status: freeeapigen.ReceiptStatusConfirmed}, "x.pdf", nil)
+	if _, err := e.run("--company", "1", "expense", "create", fmt.Sprint(noAmount)); err == nil || !strings.Contains(err.Error(), "発行日") {
+		t.Errorf("expense create without metadata: error = %v, want error", err)
+	}
+}
+
+func TestE2EPaymentRequestCreate(t *testing.T) {
+	srv := newFakeServer()
+	srv.AddPartner(fake.Partner{ID: 20, CompanyID: 1, Name: "株式会社サンプル", LongName: "株式会社サンプル商事"})
+	receiptID := srv.AddReceipt(1, freeeapigen.Receipt{
+		CreatedAt:        "2025-04-11T10:00:00+09:00",
+		Status:           freeeapigen.ReceiptStatusConfirmed,
+		DocumentType:     ptr(freeeapigen.ReceiptDocumentType("invoice")),
+		QualifiedInvoice: ptr(freeeapigen.ReceiptQualifiedInvoiceQualified),
+		ReceiptMetadatum: receiptMetadatum(33000, "2025-04-10", "株式会社サンプル商事"),
+	}, "invoice.pdf", nil)
+	e := newE2E(t, srv)
+
+	out, err := e.run("--company", "1", "payment-request", "create", "--from-receipt", fmt.Sprint(receiptID), "--approval-flow-route-id", "5", "--due-date", "2025-05-31")
+	if err != nil {
+		t.Fatalf("payment-request create: %v", err)
+	}
+	var id int64
+	if _, err := fmt.Sscan(out, &id); err != nil {
+		t.Fatalf("payment-request create output = %q, want payment request ID", out)
+	}
+	got, ok := srv.PaymentRequest(id)
+	if !ok {
+		t.Fatalf("payment request %d is not created", id)
+	}
+	p := got.PaymentRequest
+	if p.Title != "株式会社サンプル商事 請求書 2025-04-10" || p.IssueDate != "2025-04-10" || deref(p.PaymentDate, "") != "2025-05-31" {
+		t.Errorf("created payment request = %+v", p)
+	}
+	if deref(p.PartnerId, 0) != 20 || p.TotalAmount != 33000 || p.Status != freeeapigen.PaymentRequestResponsePaymentRequestStatusDraft {
+		t.Errorf("created payment request = %+v", p)
+	}
+	if p.QualifiedInvoiceStatus == nil || *p.QualifiedInvoiceStatus != "qualified" {
+		t.Errorf("qualified_invoice_status = %v, want qualified", p.QualifiedInvoiceStatus)
+	}
+	if !reflect.DeepEqual(p.ReceiptIds, []int64{receiptID}) {
+		t.Errorf("receipt_ids = %v, want [%d]", p.ReceiptIds, receiptID)
+	}
+
+	if _, err := e.run("--company", "1", "payment-request", "create", "--from-receipt", "1001", "--approval-flow-route-id", "5"); err == nil || !strings.Contains(err.Error(), "請求書") {
+		t.Errorf("payment-request create from a non-invoice receipt: error = %v, want error", err)
+	}
+}
+
+func TestE2EMatch(t *testing.T) {
+	srv := newFakeServer()
+	txnID := srv.AddWalletTxn(1, freeeapigen.WalletTxn{
+		Date: "2025-04-02", Amount: 1100, Description: "VISA テスト文具店", EntrySide: freeeapigen.WalletTxnEntrySideExpense,
+		Status: 1, WalletableType: "credit_card", WalletableId: 3,
+	})
+	srv.AddWalletTxn(1, freeeapigen.WalletTxn{
+		Date: "2025-04-20", Amount: 5000, Description: "電気料金", EntrySide: freeeapigen.WalletTxnEntrySideExpense,
+		Status: 1, WalletableType: "bank_account", WalletableId: 4,
+	})
+	// A pair whose amounts differ by less than 1% is suggested, but no deal is created from it.
+	offReceiptID := srv.AddReceipt(1, freeeapigen.Receipt{
+		CreatedAt:        "2025-04-11T10:00:00+09:00",
+		Status:           freeeapigen.ReceiptStatusConfirmed,
+		ReceiptMetadatum: receiptMetadatum(2000, "2025-04-10", "サンプル商事"),
+	}, "off.pdf", nil)
+	offTxnID := srv.AddWalletTxn(1, freeeapigen.WalletTxn{
+		Date: "2025-04-10", Amount: 2010, Description: "サンプル商事", EntrySide: freeeapigen.WalletTxnEntrySideExpense,
+		Status: 1, WalletableType: "bank_account", WalletableId: 4,
+	})
+	e := newE2E(t, srv)
+	period := []string{"--start-date", "2025-04-01", "--end-date", "2025-04-30"}
+
+	out, err := e.run(append([]string{"--company", "1", "match", "--format", "json"}, period...)...)
+	if err != nil {
+		t.Fatalf("match: %v", err)
+	}
+	type suggestion struct {
+		Score     float64            `json:"score"`
+		Receipt   struct{ ID int64 } `json:"receipt"`
+		WalletTxn struct{ ID int64 } `json:"wallet_txn"`
+	}
+	var got []suggestion
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var s suggestion
+		if err := json.Unmarshal([]byte(line), &s); err != nil {
+			t.Fatalf("match output %q: %v", out, err)
+		}
+		got = append(got, s)
+	}
+	if len(got) != 2 || got[0].Receipt.ID != 1001 || got[0].WalletTxn.ID != txnID || got[0].Score < 0.9 ||
+		got[1].Receipt.ID != offReceiptID || got[1].WalletTxn.ID != offTxnID {
+		t.Fatalf("match suggestions = %+v, want receipt 1001 and txn %d, receipt %d and txn %d", got, txnID, offReceiptID, offTxnID)
+	}
+
+	if _, err := e.run(append([]string{"--company", "1", "match", "--create-deals", "--yes", "--account-item-id", "100", "--tax-code", "136"}, period...)...); err != nil {
+		t.Fatalf("match --create-deals: %v", err)
+	}
+	var params freeeapigen.DealCreateParams
+	if err := json.Unmarshal(e.lastRequest(http.MethodPost, "/api/1/deals").Body, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.Payments == nil || len(*params.Payments) != 1 || (*params.Payments)[0].FromWalletableId != 3 || (*params.Payments)[0].Amount != 1100 {
+		t.Errorf("deal payments = %+v, want 1100 from the credit card 3", params.Payments)
+	}
+	if params.ReceiptIds == nil || !reflect.DeepEqual(*params.ReceiptIds, []int64{1001}) {
+		t.Errorf("deal receipt_ids = %v, want [1001]", params.ReceiptIds)
+	}
+	if n := e.requestCount(http.MethodPost, "/api/1/deals"); n != 1 {
+		t.Errorf("match --create-deals created %d deals, want 1 without the pair of different amounts", n)
+	}
+
+	// The receipt is attached to the deal, so it is no longer a candidate.
+	out, err = e.run(append([]string{"--company", "1", "match"}, period...)...)
+	if err != nil {
+		t.Fatalf("match: %v", err)
+	}
+	if strings.Contains(out, "1001") || !strings.Contains(out, fmt.Sprint(offReceiptID)) {
+		t.Errorf("match output after creating the deal = %q, want only receipt %d", out, offReceiptID)
+	}
+}
+
+func TestE2EShowLinks(t *testing.T) {
+	srv := newFakeServer()
+	var d freeeapigen.Deal
+	err := json.Unmarshal([]byte(`{
+		"issue_date": "2025-04-01", "type": "expense", "status": "unsettled",
+		"details": [{"id": 1, "entry_side": "debit", "account_item_id": 100, "tax_code": 136, "amount": 1100}]
+	}`), &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dealID := srv.AddDeal(1, d, 1001)
+	d.IssueDate = "2025-05-10"
+	otherDealID := srv.AddDeal(1, d)
+	// A deal outside the window is not listed.
+	d.IssueDate = "2026-04-01"
+	srv.AddDeal(1, d, 1001)
+	e := newE2E(t, srv)
+
+	out, err := e.run("--company", "1", "show", "--links", "1001")
+	if err != nil {
+		t.Fatalf("show --links: %v", err)
+	}
+	if want := fmt.Sprintf("deal  %d  2025-04-01  1100", dealID); !strings.Contains(out, want) {
+		t.Errorf("show --links output = %q, want %q", out, want)
+	}
+	if strings.Contains(out, "2026-04-01") {
+		t.Errorf("show --links output = %q, want no deals outside the window", out)
+	}
+	req := e.lastRequest(http.MethodGet, "/api/1/deals")
+	if got := req.Query["end_issue_date"]; len(got) != 1 || got[0] != "2025-07-31" {
+		t.Errorf("last end_issue_date = %v, want 2025-07-31", got)
+	}
+
+	// The second lookup is served from the cache.
+	deals := e.requestCount(http.MethodGet, "/api/1/deals")
+	if _, err := e.run("--company", "1", "show", "--links", "1001"); err != nil {
+		t.Fatalf("show --links: %v", err)
+	}
+	if n := e.requestCount(http.MethodGet, "/api/1/deals"); n != deals {
+		t.Errorf("deals were fetched %d times again, want the cache to be used", n-deals)
+	}
+
+	// Attaching the receipt invalidates the cache, so the new link is shown at once.
+	if _, err := e.run("--company", "1", "attach", "--deal", fmt.Sprint(otherDealID), "1001"); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	out, err = e.run("--company", "1", "show", "--links", "1001")
+	if err != nil {
+		t.Fatalf("show --links: %v", err)
+	}
+	if want := fmt.Sprintf("deal  %d  2025-05-10  1100", otherDealID); !strings.Contains(out, want) {
+		t.Errorf("show --links after attach = %q, want %q", out, want)
+	}
+	if _, err := e.run("--company", "1", "detach", "--deal", fmt.Sprint(otherDealID), "1001"); err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+	if out, err = e.run("--company", "1", "show", "--links", "1001"); err != nil || strings.Contains(out, "2025-05-10") {
+		t.Errorf("show --links after detach = %q, %v, want the deal to be gone", out, err)
+	}
+	if _, err := e.run("--company", "1", "expense", "create", "--issue-date", "2025-04-05", "1001"); err != nil {
+		t.Fatalf("expense create: %v", err)
+	}
+	if out, err = e.run("--company", "1", "show", "--links", "1001"); err != nil || !strings.Contains(out, "expense_application") {
+		t.Errorf("show --links after expense create = %q, %v, want the expense application", out, err)
+	}
+
+	out, err = e.run("--company", "1", "show", "--links", "--refresh", "--format", "json", "1001")
+	if err != nil {
+		t.Fatalf("show --links --refresh: %v", err)
+	}
+	var got struct {
+		ID    int64         `json:"id"`
+		Links []receiptLink `json:"links"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal show output %q: %v", out, err)
+	}
+	if got.ID != 1001 || len(got.Links) != 2 || got.Links[0].Type != linkTypeDeal || got.Links[1].Type != linkTypeExpenseApplication {
+		t.Errorf("show --links --refresh = %+v, want the deal and the expense application", got)
+	}
+}
+
+func TestE2ESync(t *testing.T) {
+	srv := newFakeServer()
+	e := newE2E(t, srv)
+	dir := filepath.Join(t.TempDir(), "mirror")
+	// callAPI changes the fake server state as if done on the freee web app.
+	callAPI := func(method, target, body string) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code >= 300 {
+			t.Fatalf("%s %s: %d %s", method, target, rec.Code, rec.Body)
+		}
+	}
+
+	if _, err := e.run("--company", "1", "sync", dir); err == nil {
+		t.Error("first sync without --since: error = nil, want error")
+	}
+	out, err := e.run("--company", "1", "sync", "--since", "2025-04-01", dir)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if !strings.Contains(out, "ダウンロードしました: "+filepath.Join("2025-04", "1001.pdf")) {
+		t.Errorf("sync output = %q, want the receipt to be downloaded", out)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "2025-04", "1001.pdf")); err != nil || string(b) != "%PDF-1.4\n" {
+		t.Errorf("synced file = %q, %v, want the receipt content", b, err)
+	}
+
+	// The second sync only lists receipts created since the last sync.
+	downloads := e.requestCount(http.MethodGet, "/api/1/receipts/1001/download")
+	out, err = e.run("--company", "1", "sync", dir)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if n := e.requestCount(http.MethodGet, "/api/1/receipts/1001/download"); n != downloads {
+		t.Errorf("the receipt was downloaded %d times again, want none", n-downloads)
+	}
+	if want := "同期しました: 新規 0 件、更新 0 件、削除 0 件、無視 0 件\n"; out != want {
+		t.Errorf("sync output = %q, want %q", out, want)
+	}
+
+	callAPI(http.MethodPut, "/api/1/receipts/1001", `{"company_id": 1, "description": "文房具"}`)
+	out, err = e.run("--company", "1", "sync", "--full", dir)
+	if err != nil {
+		t.Fatalf("sync --full: %v", err)
+	}
+	if !strings.Contains(out, "更新 1 件") {
+		t.Errorf("sync --full output = %q, want the metadata to be updated", out)
+	}
+	var meta freeeapigen.Receipt
+	b, _ := os.ReadFile(filepath.Join(dir, "2025-04", "1001.json"))
+	if err := json.Unmarshal(b, &meta); err != nil || deref(meta.Description, "") != "文房具" {
+		t.Errorf("metadata = %s, want the updated description", b)
+	}
+
+	callAPI(http.MethodDelete, "/api/1/receipts/1001?company_id=1", "")
+	out, err = e.run("--company", "1", "sync", "--full", dir)
+	if err != nil {
+		t.Fatalf("sync --full: %v", err)
+	}
+	if !strings.Contains(out, "削除 1 件") {
+		t.Errorf("sync --full output = %q, want the receipt to be marked as deleted", out)
+	}
+	b, _ = os.ReadFile(filepath.Join(dir, "2025-04", "1001.json"))
+	if err := json.Unmarshal(b, &meta); err != nil || meta.Status != freeeapigen.ReceiptStatusDeleted {
+		t.Errorf("metadata = %s, want the deleted status", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2025-04", "1001.pdf")); err != nil {
+		t.Errorf("the file of the deleted receipt is removed: %v", err)
+	}
+
+	if _, err := e.run("--company", "2", "sync", dir); err == nil {
+		t.Error("sync of another company into the same directory: error = nil, want error")
+	}
+}
+
+func TestE2ESearch(t *testing.T) {
+	srv := newFakeServer()
+	cafeID := srv.AddReceipt(1, freeeapigen.Receipt{
+		CreatedAt:        "2025-05-11T10:00:00+09:00",
+		Status:           freeeapigen.ReceiptStatusConfirmed,
+		Origin:           freeeapigen.PublicApi,
+		MimeType:         "image/jpeg",
+		Description:      ptr("打ち合わせ"),
+		ReceiptMetadatum: receiptMetadatum(5500, "2025-05-10", "Sample Cafe"),
+	}, "cafe.jpg", []byte("jpeg"))
+	e := newE2E(t, srv)
+	search := func(args ...string) string {
+		t.Helper()
+		out, err := e.run(append([]string{"--company", "1", "search", "--format", "json", "--fields", "id"}, args...)...)
+		if err != nil {
+			t.Fatalf("search %v: %v", args, err)
+		}
+		return out
+	}
+
+	if out := search(); out != "" {
+		t.Errorf("search before indexing = %q, want nothing", out)
+	}
+	// Receipts fetched by show are indexed.
+	if _, err := e.run("--company", "1", "show", "1001"); err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	if out, want := search("--partner", "文具"), `{"id":1001}`+"\n"; out != want {
+		t.Errorf("search --partner = %q, want %q", out, want)
+	}
+
+	if out, want := search("--refresh", "--since", "2025-01-01"), fmt.Sprintf(`{"id":1001}`+"\n"+`{"id":%d}`+"\n", cafeID); out != want {
+		t.Errorf("search --refresh = %q, want %q", out, want)
+	}
+	lists := e.requestCount(http.MethodGet, "/api/1/receipts")
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--min-amount", "5000"}, fmt.Sprintf(`{"id":%d}`+"\n", cafeID)},
+		{[]string{"--max-amount", "5000", "--start-issue-date", "2025-04-01"}, `{"id":1001}` + "\n"},
+		{[]string{"--text", "打ち合わせ", "--status", "confirmed"}, fmt.Sprintf(`{"id":%d}`+"\n", cafeID)},
+		{[]string{"--end-issue-date", "2025-03-31"}, ""},
+	} {
+		if out := search(tt.args...); out != tt.want {
+			t.Errorf("search %v = %q, want %q", tt.args, out, tt.want)
+		}
+	}
+	if n := e.requestCount(http.MethodGet, "/api/1/receipts"); n != lists {
+		t.Errorf("search without --refresh called the API %d times", n-lists)
+	}
+}
+
+func TestE2EExportEvidence(t *testing.T) {
+	srv := newFakeServer()
+	add := func(createdAt string, amount int64, issueDate, partner string) int64 {
+		r := freeeapigen.Receipt{
+			CreatedAt: createdAt,
+			Status:    freeeapigen.ReceiptStatusConfirmed,
+			Origin:    freeeapigen.PublicApi,
+			MimeType:  "application/pdf",
+		}
+		if amount != 0 {
+			r.ReceiptMetadatum = receiptMetadatum(amount, issueDate, partner)
+		}
+		return srv.AddReceipt(1, r, "receipt.pdf", []byte("%PDF-1.4\n"))
+	}
+	// The same keys as receipt 1001, and a partner name with a path separator.
+	sameID := add("2025-04-03T10:00:00+09:00", 1100, "2025-04-01", "テスト文具店")
+	slashID := add("2025-04-20T10:00:00+09:00", 2200, "2025-04-15", "A/B商会")
+	// A partner name too long for a file name is truncated in the file name only.
+	longPartner := strings.Repeat("長", 100)
+	longID := add("2025-04-25T10:00:00+09:00", 4400, "2025-04-25", longPartner)
+	add("2025-05-02T10:00:00+09:00", 3300, "2025-05-01", "対象外")
+	// A receipt without the metadata is not exported.
+	add("2025-04-05T10:00:00+09:00", 0, "", "")
+	failDownload := slashID
+	e := newE2E(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == fmt.Sprintf("/api/1/receipts/%d/download", failDownload) {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		srv.ServeHTTP(w, r)
+	}))
+
+	// A failed export leaves neither the output directory nor the temporary one.
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "evidence")
+	if _, err := e.run("--company", "1", "export", "evidence", "--from", "2025-04-01", "--to", "2025-04-30", "-o", dir); err == nil {
+		t.Fatal("export evidence with a failed download: error = nil, want error")
+	}
+	if entries, err := os.ReadDir(parent); err != nil || len(entries) != 0 {
+		t.Fatalf("entries after a failed export = %v, %v, want none", entries, err)
+	}
+
+	failDownload = 0
+	out, err := e.run("--company", "1", "export", "evidence", "--from", "2025-04-01", "--to", "2025-04-30", "-o", dir)
+	if err != nil {
+		t.Fatalf("export evidence: %v", err)
+	}
+	if want := "証憑 4 件を " + dir + " に出力しました\n"; out != want {
+		t.Errorf("export evidence output = %q, want %q", out, want)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "index.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\uFEFF取引年月日,取引金額,取引先,証憑ID,ファイル名\n" +
+		"2025-04-01,1100,テスト文具店,1001,20250401_テスト文具店_1100.pdf\n" +
+		fmt.Sprintf("2025-04-01,1100,テスト文具店,%d,20250401_テスト文具店_1100_2.pdf\n", sameID) +
+		fmt.Sprintf("2025-04-15,2200,A/B商会,%d,20250415_A／B商会_2200.pdf\n", slashID) +
+		fmt.Sprintf("2025-04-25,4400,%s,%d,20250425_%s_4400.pdf\n", longPartner, longID, strings.Repeat("長", 66))
+	if string(b) != want {
+		t.Errorf("index.csv = %q, want %q", b, want)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "20250401_テスト文具店_1100.pdf")); err != nil || string(b) != "%PDF-1.4\n" {
+		t.Errorf("exported file = %q, %v, want the receipt content", b, err)
+	}
+
+	if _, err := e.run("--company", "1", "export", "evidence", "--from", "2025-04-01", "--to", "2025-04-30", "-o", dir); err == nil {
+		t.Error("export into a non-empty directory: error = nil, want error")
+	}
+
+	// An existing file is neither removed nor replaced.
+	file := filepath.Join(parent, "evidence.zip")
+	if err := os.WriteFile(file, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	downloads := e.requestCount(http.MethodGet, fmt.Sprintf("/api/1/receipts/%d/download", sameID))
+	if _, err := e.run("--company", "1", "export", "evidence", "--from", "2025-04-01", "--to", "2025-04-30", "-o", file); err == nil {
+		t.Error("export into a file: error = nil, want error")
+	}
+	if b, err := os.ReadFile(file); err != nil || string(b) != "keep" {
+		t.Errorf("file after export = %q, %v, want it unchanged", b, err)
+	}
+	if n := e.requestCount(http.MethodGet, fmt.Sprintf("/api/1/receipts/%d/download", sameID)); n != downloads {
+		t.Errorf("export into a file downloaded the receipts")
+	}
+}
+
+func TestE2EAudit(t *testing.T) {
+	srv := newFakeServer()
+	var d freeeapigen.Deal
+	if err := json.Unmarshal([]byte(`{
+		"issue_date": "2025-04-01", "type": "expense", "status": "unsettled",
+		"details": [{"id": 1, "account_item_id": 100, "tax_code": 136, "amount": 1100, "entry_side": "debit"}]
+	}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	dealID := srv.AddDeal(1, d)
+	e := newE2E(t, srv)
+	path := filepath.Join(t.TempDir(), "invoice.pdf")
+	content := []byte("%PDF-1.4\ninvoice\n")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := e.run("--company", "1", "upload", "--description", "4月分", path)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	receiptID, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		t.Fatalf("upload output = %q: %v", out, err)
+	}
+	if _, err := e.run("--company", "1", "attach", "--deal", fmt.Sprint(dealID), fmt.Sprint(receiptID), "1001"); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if _, err := e.run("--company", "1", "detach", "--deal", fmt.Sprint(dealID), "1001"); err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+
+	out, err = e.run("audit", "log", "--format", "json")
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	var entries []audit.Entry
+	for line := range strings.Lines(out) {
+		var entry audit.Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("unmarshal %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("audit log = %d entries, want 3:\n%s", len(entries), out)
+	}
+	upload, attach, detach := entries[0], entries[1], entries[2]
+	if upload.Operation != "upload" || upload.CompanyID != 1 || !reflect.DeepEqual(upload.ReceiptIDs, []int64{receiptID}) ||
+		upload.FileHash != audit.HashFile(content) || upload.User == "" {
+		t.Errorf("upload entry = %+v", upload)
+	}
+	var params map[string]any
+	if err := json.Unmarshal(upload.Params, &params); err != nil || params["receipt"] != "invoice.pdf" || params["description"] != "4月分" {
+		t.Errorf("upload params = %s, %v", upload.Params, err)
+	}
+	target := fmt.Sprintf("deal:%d", dealID)
+	if attach.Operation != "attach" || attach.Target != target || !reflect.DeepEqual(attach.ReceiptIDs, []int64{receiptID, 1001}) {
+		t.Errorf("attach entry = %+v", attach)
+	}
+	if detach.Operation != "detach" || detach.Target != target || !reflect.DeepEqual(detach.ReceiptIDs, []int64{1001}) {
+		t.Errorf("detach entry = %+v", detach)
+	}
+
+	out, err = e.run("audit", "log", "--receipt", "1001")
+	if err != nil {
+		t.Fatalf("audit log --receipt: %v", err)
+	}
+	if strings.Contains(out, "upload") || !strings.Contains(out, "attach") || !strings.Contains(out, "detach") {
+		t.Errorf("audit log --receipt output:\n%s", out)
+	}
+
+	out, err = e.run("audit", "verify")
+	if err != nil {
+		t.Fatalf("audit verify: %v", err)
+	}
+	if !strings.Contains(out, "3 件") || !strings.Contains(out, detach.Hash) {
+		t.Errorf("audit verify output = %q", out)
+	}
+
+	logPath := filepath.Join(os.Getenv("XDG_STATE_HOME"), "ffbox", "audit.jsonl")
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(b), `"description":"4月分"`, `"description":"5月分"`, 1)
+	if err := os.WriteFile(logPath, []byte(tampered), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.run("audit", "verify"); err == nil || !strings.Contains(err.Error(), "seq 1") {
+		t.Errorf("audit verify after tampering: error = %v, want an error at seq 1", err)
+	}
+}
+
+func TestE2EInvoiceRegistrationNumber(t *testing.T) {
+	srv := newFakeServer()
+	invalidID := srv.AddReceipt(1, freeeapigen.Receipt{
+		CreatedAt:                 "2025-04-10T10:00:00+09:00",
+		Status:                    freeeapigen.ReceiptStatusConfirmed,
+		InvoiceRegistrationNumber: ptr("T1234567890123"),
+	}, "invalid.pdf", nil)
+	e := newE2E(t, srv)
+	path := filepath.Join(t.TempDir(), "invoice.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A typo is caught before the request is sent.
+	if _, err := e.run("--company", "1", "upload", "--invoice-registration-number", "T7000012050003", path); err == nil || !strings.Contains(err.Error(), "チェックデジット") {
+		t.Errorf("upload with a wrong check digit: error = %v", err)
+	}
+	if n := e.requestCount(http.MethodPost, "/api/1/receipts"); n != 0 {
+		t.Errorf("upload with a wrong check digit sent %d requests", n)
+	}
+	out, err := e.run("--company", "1", "upload", "--invoice-registration-number", "T7000012050002", path)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	uploadedID, _ := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if got, _, _ := srv.Receipt(uploadedID); deref(got.InvoiceRegistrationNumber, "") != "T7000012050002" {
+		t.Errorf("invoice registration number = %v, want T7000012050002", got.InvoiceRegistrationNumber)
+	}
+
+	// Metadata not specified are kept.
+	if _, err := e.run("--company", "1", "update", "--amount", "2200", "--qualified-invoice", "qualified", "1001"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, _, _ := srv.Receipt(1001)
+	if m := got.ReceiptMetadatum; m == nil || deref(m.Amount, 0) != 2200 || deref(m.IssueDate, "") != "2025-04-01" || deref(m.PartnerName, "") != "テスト文具店" {
+		t.Errorf("metadatum after update = %+v", got.ReceiptMetadatum)
+	}
+	if deref(got.QualifiedInvoice, "") != freeeapigen.ReceiptQualifiedInvoiceQualified {
+		t.Errorf("qualified invoice = %v, want qualified", got.QualifiedInvoice)
+	}
+	if _, err := e.run("--company", "1", "update", "--invoice-registration-number", "7000012050002", "1001"); err == nil || !strings.Contains(err.Error(), "13桁") {
+		t.Errorf("update with an invalid format: error = %v", err)
+	}
+	if _, err := e.run("--company", "1", "update", "1001"); err == nil {
+		t.Error("update without flags: error = nil, want error")
+	}
+
+	period := []string{"--start-date", "2025-04-01", "--end-date", "2025-04-30"}
+	invalidNumbers := func() []int64 {
+		out, err := e.run(append([]string{"--company", "1", "lint", "--format", "json"}, period...)...)
+		if err == nil {
+			t.Error("lint: error = nil, want the issues")
+		}
+		var ids []int64
+		for line := range strings.Lines(out) {
+			var issue lintIssue
+			if err := json.Unmarshal([]byte(line), &issue); err != nil {
+				t.Fatalf("unmarshal %q: %v", line, err)
+			}
+			if issue.Rule == "invalid_invoice_registration_number" {
+				ids = append(ids, issue.ReceiptID)
+			}
+		}
+		return ids
+	}
+	if got := invalidNumbers(); !reflect.DeepEqual(got, []int64{invalidID}) {
+		t.Errorf("lint: invalid registration numbers = %v, want [%d]", got, invalidID)
+	}
+	if _, err := e.run("--company", "1", "update", "--invoice-registration-number", "T7000012050002", fmt.Sprint(invalidID)); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := invalidNumbers(); got != nil {
+		t.Errorf("lint after fix: invalid registration numbers = %v, want none", got)
+	}
+}
+
+func TestE2ELint(t *testing.T) {
+	srv := fake.New()
+	srv.Token = testToken
+	srv.AddCompany(fake.Company{ID: 1, Name: "株式会社テスト", DisplayName: "株式会社テスト", CompanyNumber: "1111111111",
+		FiscalYears: [][2]string{{"2024-04-01", "2025-03-31"}, {"2025-04-01", "2026-03-31"}}})
+	add := func(status freeeapigen.ReceiptStatus, qualified *freeeapigen.ReceiptQualifiedInvoice, number, docType string, amount int64, issueDate string) int64 {
+		r := freeeapigen.Receipt{CreatedAt: "2025-04-10T10:00:00+09:00", Status: status, QualifiedInvoice: qualified}
+		if number != "" {
+			r.InvoiceRegistrationNumber = &number
+		}
+		if docType != "" {
+			r.DocumentType = ptr(freeeapigen.ReceiptDocumentType(docType))
+		}
+		if amount != 0 {
+			r.ReceiptMetadatum = receiptMetadatum(amount, issueDate, "テスト商店")
+		}
+		return srv.AddReceipt(1, r, "r.pdf", nil)
+	}
+	qualified := ptr(freeeapigen.ReceiptQualifiedInvoiceQualified)
+	unselected := ptr(freeeapigen.ReceiptQualifiedInvoiceUnselected)
+	confirmed := freeeapigen.ReceiptStatusConfirmed
+	add(confirmed, qualified, "T7000012050002", "invoice", 5500, "2025-04-01")
+	small := add(confirmed, unselected, "", "receipt", 5000, "2025-04-02")
+	large := add(confirmed, nil, "", "receipt", 22000, "2025-04-03")
+	// Issued at the end of the previous fiscal year and registered in the next one.
+	march := add(confirmed, qualified, "", "invoice", 3300, "2025-03-28")
+	// Issued before any fiscal year of the company, probably a typo.
+	typo := add(confirmed, unselected, "", "receipt", 3300, "2015-04-05")
+	bare := add(confirmed, nil, "", "", 0, "")
+	add(freeeapigen.ReceiptStatusIgnored, nil, "", "", 0, "")
+	e := newE2E(t, srv)
+
+	lint := func(args ...string) (map[int64][]string, error) {
+		t.Helper()
+		args = append([]string{"--company", "1", "lint", "--start-date", "2025-04-01", "--end-date", "2025-04-30", "--format", "json"}, args...)
+		out, err := e.run(args...)
+		rules := map[int64][]string{}
+		for line := range strings.Lines(out) {
+			var issue lintIssue
+			if err := json.Unmarshal([]byte(line), &issue); err != nil {
+				t.Fatalf("unmarshal %q: %v", line, err)
+			}
+			rules[issue.ReceiptID] = append(rules[issue.ReceiptID], issue.Rule)
+		}
+		return rules, err
+	}
+	got, err := lint()
+	if err == nil || !strings.Contains(err.Error(), "証憑 6 件のうち、7 件の問題") {
+		t.Errorf("lint: error = %v", err)
+	}
+	want := map[int64][]string{
+		large: {"unselected_qualified_invoice"},
+		march: {"missing_invoice_registration_number"},
+		typo:  {"issue_date_outside_fiscal_year"},
+		bare:  {"missing_issue_date", "missing_partner_name", "missing_amount", "missing_document_type"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lint = %v, want %v", got, want)
+	}
+
+	if got, _ := lint("--qualified-invoice-threshold", "3000"); !reflect.DeepEqual(got[small], []string{"unselected_qualified_invoice"}) {
+		t.Errorf("lint --qualified-invoice-threshold 3000: issues of receipt %d = %v", small, got[small])
+	}
+	if _, err := e.run("config", "set", "lint.qualified_invoice_threshold", "30000"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := lint(); got[large] != nil {
+		t.Errorf("lint with threshold 30000: issues of receipt %d = %v, want none", large, got[large])
+	}
+
+	out, err := e.run("--company", "1", "lint", "--start-date", "2025-04-01", "--end-date", "2025-04-30")
+	if err == nil || !strings.HasPrefix(out, "RECEIPT") || !strings.Contains(out, "書類の種類が設定されていません") {
+		t.Errorf("lint table output = %q, %v", out, err)
+	}
+}
+
+func TestE2EClassify(t *testing.T) {
+	srv := newFakeServer()
+	awsID := srv.AddReceipt(1, freeeapigen.Receipt{
+		CreatedAt:        "2025-04-05T10:00:00+09:00",
+		Status:           freeeapigen.ReceiptStatusConfirmed,
+		Origin:           freeeapigen.ReceiptOrigin("mail"),
+		MimeType:         "application/pdf",
+		ReceiptMetadatum: receiptMetadatum(5500, "2025-04-01", "Amazon Web Services Japan"),
+	}, "aws.pdf", nil)
+	e := newE2E(t, srv)
+	rulesPath := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "ffbox", "rules.toml")
+	if err := os.MkdirAll(filepath.Dir(rulesPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(rulesPath, []byte(`
+[[rule]]
+name = "aws"
+[rule.match]
+partner = "(?i)^amazon web services"
+origin = "mail"
+[rule.set]
+document_type = "invoice"
+qualified_invoice = "qualified"
+tags = ["cloud"]
+
+[[rule]]
+name = "uploaded-invoices"
+[rule.match]
+filename = "*_invoice.pdf"
+[rule.set]
+document_type = "invoice"
+description = "請求書"
+tags = ["upload"]
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	period := []string{"--since", "2025-04-01", "--until", "2025-04-30"}
+
+	out, err := e.run(append([]string{"--company", "1", "classify", "--dry-run"}, period...)...)
+	if err != nil {
+		t.Fatalf("classify --dry-run: %v", err)
+	}
+	want := fmt.Sprintf(`証憑 %d（ルール: aws）
+  document_type: null -> "invoice"
+  qualified_invoice: null -> "qualified"
+  description: null -> "#cloud"
+更新対象の証憑: 1 件（--dry-run のため更新していません）
+`, awsID)
+	if out != want {
+		t.Errorf("classify --dry-run output = %q, want %q", out, want)
+	}
+	if n := e.requestCount(http.MethodPut, fmt.Sprintf("/api/1/receipts/%d", awsID)); n != 0 {
+		t.Errorf("classify --dry-run sent %d updates", n)
+	}
+
+	if _, err := e.run(append([]string{"--company", "1", "classify"}, period...)...); err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	got, _, _ := srv.Receipt(awsID)
+	if deref(got.DocumentType, "") != "invoice" || deref(got.QualifiedInvoice, "") != "qualified" || deref(got.Description, "") != "#cloud" {
+		t.Errorf("classified receipt = %+v", got)
+	}
+	if out, err := e.run(append([]string{"--company", "1", "classify"}, period...)...); err != nil || out != "証憑 0 件を更新しました\n" {
+		t.Errorf("classify again = %q, %v, want no changes", out, err)
+	}
+
+	// On upload, the flags take precedence over the rules.
+	path := filepath.Join(t.TempDir(), "2025-04_invoice.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.run("--company", "1", "upload", "--description", "4月分", path); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	req := e.lastRequest(http.MethodPost, "/api/1/receipts")
+	if req.Fields["document_type"] != "invoice" || req.Fields["description"] != "4月分 #upload" {
+		t.Errorf("upload fields = %v", req.Fields)
+	}
+	if _, err := e.run("--company", "1", "upload", "--no-rules", path); err != nil {
+		t.Fatalf("upload --no-rules: %v", err)
+	}
+	if req := e.lastRequest(http.MethodPost, "/api/1/receipts"); len(req.Fields) != 1 {
+		t.Errorf("upload --no-rules fields = %v", req.Fields)
+	}
+
+	if _, err := e.run(append([]string{"--company", "1", "classify", "--rules", "missing.toml"}, period...)...); err == nil {
+		t.Error("classify with a missing rules file: error = nil, want error")
+	}
+
+	// A relative rules_file in a project .ffbox.toml is relative to that file.
+	if err := os.MkdirAll("rules", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(".ffbox.toml", []byte("[classify]\nrules_file = \"rules/project.toml\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join("rules", "project.toml"), []byte(`
+[[rule]]
+name = "project"
+[rule.match]
+partner = "(?i)^amazon web services"
+[rule.set]
+description = "AWS"
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = e.run(append([]string{"--company", "1", "classify", "--dry-run"}, period...)...)
+	if err != nil {
+		t.Fatalf("classify with project rules: %v", err)
+	}
+	if want := fmt.Sprintf("証憑 %d（ルール: project）\n", awsID); !strings.HasPrefix(out, want) {
+		t.Errorf("classify with project rules output = %q, want %q", out, want)
+	}
+}
+
+func TestE2EWatch(t *testing.T) {
+	srv := newFakeServer()
+	// The fake server fails to create a receipt from broken.pdf.
+	e := newE2E(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/api/1/receipts" {
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if bytes.Contains(body, []byte(`filename="broken.pdf"`)) {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"scan_001.pdf":          "%PDF-1.4\n",
+		"scan_002.PNG":          "\x89PNG\r\n\x1a\n",
+		"broken.pdf":            "%PDF-1.4\n",
+		"notes.txt":             "not a receipt",
+		".scan_003.pdf":         "%PDF-1.4\n",
+		"uploaded/scan_001.pdf": "%PDF-1.4\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := e.run("--company", "1", "watch", "--once", "--interval", "10ms", "--document-type", "receipt", dir)
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if n := e.requestCount(http.MethodPost, "/api/1/receipts"); n != 3 {
+		t.Errorf("watch sent %d uploads, want 3", n)
+	}
+	if req := e.lastRequest(http.MethodPost, "/api/1/receipts"); req.Fields["document_type"] != "receipt" {
+		t.Errorf("upload fields = %v", req.Fields)
+	}
+	for _, want := range []string{"scan_001.pdf -> uploaded/scan_001_2.pdf", "scan_002.PNG -> uploaded/scan_002.PNG"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("watch output = %q, want %q", out, want)
+		}
+	}
+
+	for _, name := range []string{
+		"notes.txt", ".scan_003.pdf",
+		"uploaded/scan_001.pdf", "uploaded/scan_001_2.pdf", "uploaded/scan_002.PNG", "failed/broken.pdf",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("stat %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"scan_001.pdf", "scan_002.PNG", "broken.pdf"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s is left in the directory: %v", name, err)
+		}
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "ffbox-watch.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			t.Fatalf("log line = %q", line)
+		}
+		results = append(results, fields[1]+" "+fields[2]+" "+fields[3])
+		if fields[2] == "broken.pdf" && !strings.HasPrefix(fields[4], "error=") {
+			t.Errorf("log line for a failed upload = %q", line)
+		}
+	}
+	want := []string{
+		"failed broken.pdf failed/broken.pdf",
+		"uploaded scan_001.pdf uploaded/scan_001_2.pdf",
+		"uploaded scan_002.PNG uploaded/scan_002.PNG",
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("log = %q, want %q", results, want)
+	}
+
+	// Files already moved are not uploaded again.
+	if _, err := e.run("--company", "1", "watch", "--once", "--interval", "10ms", dir); err != nil {
+		t.Fatalf("watch again: %v", err)
+	}
+	if n := e.requestCount(http.MethodPost, "/api/1/receipts"); n != 3 {
+		t.Errorf("watch again sent %d uploads in total, want 3", n)
+	}
+
+	// A file whose receipt is created is moved to uploaded/ even if the audit
+	// log cannot be written, so that retrying from failed/ does not upload it twice.
+	blocker := filepath.Join(t.TempDir(), "not-a-directory")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.run("config", "set", "audit.log_file", filepath.Join(blocker, "audit.jsonl")); err != nil {
+		t.Fatalf("config set: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scan_004.pdf"), []byte("%PDF-1.4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.run("--company", "1", "watch", "--once", "--interval", "10ms", dir); err != nil {
+		t.Fatalf("watch without the audit log: %v", err)
+	}
+	if n := e.requestCount(http.MethodPost, "/api/1/receipts"); n != 4 {
+		t.Errorf("watch sent %d uploads in total, want 4", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "uploaded", "scan_004.pdf")); err != nil {
+		t.Errorf("stat uploaded/scan_004.pdf: %v", err)
+	}
+	b, err = os.ReadFile(filepath.Join(dir, "ffbox-watch.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if fields := strings.Split(lines[len(lines)-1], "\t"); len(fields) != 5 || fields[1] != "uploaded" ||
+		!strings.HasPrefix(fields[4], "receipt=") || !strings.Contains(fields[4], " warning=") {
+		t.Errorf("log line for an upload without the audit log = %q", lines[len(lines)-1])
+	}
+
+	if _, err := e.run("--company", "1", "watch", filepath.Join(dir, "missing")); err == nil {
+		t.Error("watch a missing directory: error = nil, want error")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/audit"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+var cmdExpense = &cli.Command{
+	Name:   "expense",
+	Usage:  "経費申請を操作します",
+	Before: loadAppConfig,
+	Commands: []*cli.Command{
+		cmdExpenseCreate,
+	},
+}
+
+var (
+	flagExpenseCreateTitle = &cli.StringFlag{
+		Name:  "title",
+		Usage: "申請タイトル（省略時は「経費精算 <申請日>」）",
+	}
+	flagExpenseCreateIssueDate = &cli.StringFlag{
+		Name:      "issue-date",
+		Usage:     "申請日 (yyyy-mm-dd)。省略時は当日",
+		Validator: validateDate,
+	}
+	flagExpenseCreateApprovalFlowRouteID = &cli.Int64Flag{
+		Name:  "approval-flow-route-id",
+		Usage: "申請経路ID（省略時は freee の基本経路）",
+	}
+	flagExpenseCreateApproverID = &cli.Int64Flag{
+		Name:  "approver-id",
+		Usage: "承認者のユーザーID（「承認者を指定」の経路を使用する場合）",
+	}
+	flagExpenseCreateLineTemplateID = &cli.Int64Flag{
+		Name:  "line-template-id",
+		Usage: "明細行の経費科目ID（すべての明細行に設定します）",
+	}
+	flagExpenseCreateSectionID = &cli.Int64Flag{
+		Name:  "section-id",
+		Usage: "部門ID",
+	}
+	flagExpenseCreateDescription = &cli.StringFlag{
+		Name:  "description",
+		Usage: "経費申請の備考",
+	}
+	flagExpenseCreateSubmit = &cli.BoolFlag{
+		Name:  "submit",
+		Usage: "下書きではなく申請中として作成します（--approval-flow-route-id の指定を推奨）",
+	}
+	flagExpenseCreateDryRun = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "経費申請を作成せず、送信する内容を JSON で表示します",
+	}
+)
+
+var cmdExpenseCreate = &cli.Command{
+	Name:      "create",
+	Usage:     "証憑ファイルから経費申請を作成します",
+	ArgsUsage: "<receipt-ids...>",
+	Description: `指定した証憑ファイルごとに申請行を1行ずつ持つ経費申請を作成します。
+
+各申請行の発生日と金額には証憑の発行日と金額を、内容には証憑の発行元（未設定の場合はメモ）を使用します。
+証憑に発行日または金額が設定されていない場合はエラーになります。
+
+経費申請は下書きとして作成されます。--submit を指定すると申請中として作成します。
+
+  ffbox expense create --line-template-id 123 999999999 999999998
+  ffbox expense create --submit --approval-flow-route-id 456 999999999`,
+	Flags: []cli.Flag{
+		flagExpenseCreateTitle,
+		flagExpenseCreateIssueDate,
+		flagExpenseCreateApprovalFlowRouteID,
+		flagExpenseCreateApproverID,
+		flagExpenseCreateLineTemplateID,
+		flagExpenseCreateSectionID,
+		flagExpenseCreateDescription,
+		flagExpenseCreateSubmit,
+		flagExpenseCreateDryRun,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		receiptIDs, err := parseReceiptIDs(cmd.Args().Slice())
+		if err != nil {
+			return err
+		}
+		if len(receiptIDs) == 0 {
+			return fmt.Errorf("証憑IDを指定してください")
+		}
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+
+		var templateID *int64
+		if v := cmd.Int64(flagExpenseCreateLineTemplateID.Name); v != 0 {
+			templateID = &v
+		}
+		lines := make([]expensePurchaseLine, 0, len(receiptIDs))
+		for _, id := range receiptIDs {
+			receipt, err := getReceipt(ctx, freeeapiClient, companyID, id)
+			if err != nil {
+				return err
+			}
+			line, err := newExpensePurchaseLine(receipt, templateID)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, line)
+		}
+
+		issueDate := cmd.String(flagExpenseCreateIssueDate.Name)
+		if issueDate == "" {
+			issueDate = time.Now().Format(time.DateOnly)
+		}
+		title := cmd.String(flagExpenseCreateTitle.Name)
+		if title == "" {
+			title = "経費精算 " + issueDate
+		}
+		params := freeeapigen.ExpenseApplicationCreateParams{
+			CompanyId: companyID,
+			Title:     title,
+			IssueDate: &issueDate,
+			Draft:     ptr(!cmd.Bool(flagExpenseCreateSubmit.Name)),
+		}
+		if v := cmd.Int64(flagExpenseCreateApprovalFlowRouteID.Name); v != 0 {
+			params.ApprovalFlowRouteId = &v
+		}
+		if v := cmd.Int64(flagExpenseCreateApproverID.Name); v != 0 {
+			params.ApproverId = &v
+		}
+		if v := cmd.Int64(flagExpenseCreateSectionID.Name); v != 0 {
+			params.SectionId = &v
+		}
+		if v := cmd.String(flagExpenseCreateDescription.Name); v != "" {
+			params.Description = &v
+		}
+		// PurchaseLines is a slice of nested anonymous structs, so fill it through JSON.
+		b, err := json.Marshal(lines)
+		if err != nil {
+			return fmt.Errorf("marshal purchase lines: %w", err)
+		}
+		if err := json.Unmarshal(b, &params.PurchaseLines); err != nil {
+			return fmt.Errorf("unmarshal purchase lines: %w", err)
+		}
+
+		if cmd.Bool(flagExpenseCreateDryRun.Name) {
+			b, err := json.MarshalIndent(params, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshal expense application params: %w", err)
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		resp, err := freeeapiClient.CreateExpenseApplicationWithResponse(ctx, params)
+		if err != nil {
+			return fmt.Errorf("create expense application: %w", err)
+		}
+		if resp.StatusCode() != http.StatusCreated || resp.JSON201 == nil {
+			return fmt.Errorf("got unexpected response: %s", resp.Status())
+		}
+		id := resp.JSON201.ExpenseApplication.Id
+		invalidateReceiptLinks(companyID)
+		entry := audit.Entry{
+			CompanyID:  companyID,
+			ReceiptIDs: receiptIDs,
+			Operation:  auditOpExpenseCreate,
+			Target:     fmt.Sprintf("%s:%d", linkTypeExpenseApplication, id),
+		}
+		if err := recordAudit(ctx, entry, params); err != nil {
+			return fmt.Errorf("経費申請 %d: %w", id, err)
+		}
+		fmt.Println(id)
+		return nil
+	},
+}
+
+// expensePurchaseLine は、経費申請の申請行です。
+// ExpenseApplicationCreateParams.PurchaseLines の要素と同じ JSON 形式です。
+type expensePurchaseLine struct {
+	TransactionDate         string        `json:"transaction_date"`
+	ReceiptID               int64         `json:"receipt_id"`
+	ExpenseApplicationLines []expenseLine `json:"expense_application_lines"`
+}
+
+// expenseLine は、経費申請の申請行の明細行です。
+type expenseLine struct {
+	Amount                           int64   `json:"amount"`
+	Description                      *string `json:"description,omitempty"`
+	ExpenseApplicationLineTemplateID *int64  `json:"expense_application_line_template_id,omitempty"`
+}
+
+// newExpensePurchaseLine は、証憑の発行日・金額・発行元から、その証憑を添付した申請行を作成します。
+func newExpensePurchaseLine(r *freeeapigen.Receipt, templateID *int64) (expensePurchaseLine, error) {
+	var amount *int64
+	var issueDate, partnerName *string
+	if m := r.ReceiptMetadatum; m != nil {
+		amount, issueDate, partnerName = m.Amount, m.IssueDate, m.PartnerName
+	}
+	if issueDate == nil || *issueDate == "" {
+		return expensePurchaseLine{}, fmt.Errorf("証憑 %d に発行日が設定されていません", r.Id)
+	}
+	if amount == nil {
+		return expensePurchaseLine{}, fmt.Errorf("証憑 %d に金額が設定されていません", r.Id)
+	}
+	line := expenseLine{Amount: *amount, ExpenseApplicationLineTemplateID: templateID}
+	switch {
+	case partnerName != nil && *partnerName != "":
+		line.Description = partnerName
+	case r.Description != nil && *r.Description != "":
+		line.Description = r.Description
+	}
+	return expensePurchaseLine{
+		TransactionDate:         *issueDate,
+		ReceiptID:               r.Id,
+		ExpenseApplicationLines: []expenseLine{line},
+	}, nil
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/urfave/cli/v3"
+
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+const (
+	// evidenceIndexFileName は、証憑の出力先に作成する索引 CSV の名前です。
+	evidenceIndexFileName = "index.csv"
+	// evidencePartnerMaxBytes は、ファイル名に含める取引先の最大バイト数です。
+	// 多くのファイルシステムでは、ファイル名は 255 バイトまでです。
+	evidencePartnerMaxBytes = 200
+)
+
+var cmdExport = &cli.Command{
+	Name:   "export",
+	Usage:  "証憑ファイルを出力します",
+	Before: loadAppConfig,
+	Commands: []*cli.Command{
+		cmdExportEvidence,
+	},
+}
+
+var (
+	flagExportEvidenceFrom = &cli.StringFlag{
+		Name:      "from",
+		Usage:     "出力する証憑の発行日（取引年月日）の開始日 (yyyy-mm-dd)",
+		Required:  true,
+		Validator: validateDate,
+	}
+	flagExportEvidenceTo = &cli.StringFlag{
+		Name:      "to",
+		Usage:     "出力する証憑の発行日（取引年月日）の終了日 (yyyy-mm-dd)",
+		Required:  true,
+		Validator: validateDate,
+	}
+	flagExportEvidenceOutput = &cli.StringFlag{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Usage:   "出力先のディレクトリ（省略時は evidence_<from>_<to>）。存在する場合は空である必要があります",
+	}
+)
+
+var cmdExportEvidence = &cli.Command{
+	Name:      "evidence",
+	Usage:     "電子帳簿保存法の検索要件に沿って証憑ファイルを出力します",
+	ArgsUsage: "--from <yyyy-mm-dd> --to <yyyy-mm-dd>",
+	Description: `発行日（取引年月日）が期間内の証憑ファイルを、取引年月日・取引金額・取引先で検索できる形で出力します。
+
+各ファイルは YYYYMMDD_取引先_金額.拡張子 という名前で保存され、同じディレクトリに
+取引年月日・取引金額・取引先・証憑ID・ファイル名を記載した索引（index.csv）を作成します。
+索引は表計算ソフトで開けるよう、BOM 付きの UTF-8 で出力します。
+ファイル名の取引先は 200 バイトまでに切り詰めます（索引には省略せずに記載します）。
+
+出力は一時ディレクトリに行い、完了してから出力先の名前に変えるため、
+ダウンロードなどに失敗した場合は出力先を作成しません。
+
+発行日・金額・発行元のいずれかが設定されていない証憑は出力せず、その証憑IDを表示します。
+無視・削除された証憑は出力しません。
+
+  ffbox export evidence --from 2025-04-01 --to 2026-03-31 -o evidence_FY2025`,
+	Flags: []cli.Flag{
+		flagExportEvidenceFrom,
+		flagExportEvidenceTo,
+		flagExportEvidenceOutput,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		from := cmd.String(flagExportEvidenceFrom.Name)
+		to := cmd.String(flagExportEvidenceTo.Name)
+		if to < from {
+			return fmt.Errorf("--to には --from 以降の日付を指定してください")
+		}
+		dir := cmd.String(flagExportEvidenceOutput.Name)
+		if dir == "" {
+			dir = fmt.Sprintf("evidence_%s_%s", strings.ReplaceAll(from, "-", ""), strings.ReplaceAll(to, "-", ""))
+		}
+		if exists, err := statOutputDir(dir); err != nil {
+			return err
+		} else if exists {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return fmt.Errorf("read output directory: %w", err)
+			}
+			if len(entries) > 0 {
+				return fmt.Errorf("出力先のディレクトリ %s が空ではありません", dir)
+			}
+		}
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+
+		// 証憑は発行後に登録されるため、発行日が from 以降の証憑は登録日も from 以降にある。
+		start, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			return fmt.Errorf("parse --from: %w", err)
+		}
+		receipts, err := listReceipts(ctx, freeeapiClient, companyID, start, time.Now(), nil)
+		if err != nil {
+			return err
+		}
+
+		var evidences []evidence
+		var incomplete []string
+		for _, r := range receipts {
+			if r.Status != freeeapigen.ReceiptStatusConfirmed {
+				continue
+			}
+			ev, ok := newEvidence(&r)
+			if !ok {
+				// 発行日が期間内か判断できないものも含め、検索要件を満たせない証憑として報告する。
+				if ev.issueDate == "" || (from <= ev.issueDate && ev.issueDate <= to) {
+					incomplete = append(incomplete, strconv.FormatInt(r.Id, 10))
+				}
+				continue
+			}
+			if ev.issueDate < from || to < ev.issueDate {
+				continue
+			}
+			evidences = append(evidences, ev)
+		}
+		slices.SortFunc(evidences, func(a, b evidence) int {
+			return cmp.Or(cmp.Compare(a.issueDate, b.issueDate), cmp.Compare(a.receipt.Id, b.receipt.Id))
+		})
+
+		// 途中で失敗したときに索引のない出力先が残らないよう、一時ディレクトリに出力してから名前を変える。
+		parent := filepath.Dir(filepath.Clean(dir))
+		if err := os.MkdirAll(parent, 0o755); err != nil {
+			return fmt.Errorf("create output directory: %w", err)
+		}
+		tmpDir, err := os.MkdirTemp(parent, "."+filepath.Base(dir)+".tmp-")
+		if err != nil {
+			return fmt.Errorf("create output directory: %w", err)
+		}
+		defer os.RemoveAll(tmpDir) // 名前を変えた後は何もしない
+		if err := os.Chmod(tmpDir, 0o755); err != nil {
+			return fmt.Errorf("create output directory: %w", err)
+		}
+		used := make(map[string]bool)
+		for i := range evidences {
+			ev := &evidences[i]
+			ev.fileName = uniqueFileName(used, ev.baseName(), receiptFileExt(ev.receipt.MimeType))
+			content, err := downloadReceipt(ctx, freeeapiClient, companyID, ev.receipt.Id)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(tmpDir, ev.fileName), content, 0o644); err != nil {
+				return fmt.Errorf("write %s: %w", ev.fileName, err)
+			}
+		}
+		if err := writeEvidenceIndex(filepath.Join(tmpDir, evidenceIndexFileName), evidences); err != nil {
+			return err
+		}
+		// 空の出力先のディレクトリがすでにある場合は、置き換える。
+		// 確認後に作成されたファイルは削除しない（os.Remove は空でないディレクトリを削除しない）。
+		if exists, err := statOutputDir(dir); err != nil {
+			return err
+		} else if exists {
+			if err := os.Remove(dir); err != nil {
+				return fmt.Errorf("replace output directory: %w", err)
+			}
+		}
+		if err := os.Rename(tmpDir, dir); err != nil {
+			return fmt.Errorf("rename output directory: %w", err)
+		}
+
+		if len(incomplete) > 0 {
+			fmt.Fprintf(os.Stderr, "発行日・金額・発行元のいずれかが設定されていないため、次の証憑は出力しませんでした: %s\n", strings.Join(incomplete, ", "))
+		}
+		fmt.Printf("証憑 %d 件を %s に出力しました\n", len(evidences), dir)
+		return nil
+	},
+}
+
+// statOutputDir は、出力先 dir がすでにあるかどうかを返します。
+// dir がディレクトリ以外（ファイルやシンボリックリンク）の場合はエラーを返します。
+func statOutputDir(dir string) (exists bool, err error) {
+	info, err := os.Lstat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat output directory: %w", err)
+	}
+	if !info.IsDir() {
+		return false, fmt.Errorf("出力先の %s はディレクトリではありません", dir)
+	}
+	return true, nil
+}
+
+// evidence は、出力する証憑と、その検索項目（取引年月日・取引金額・取引先）です。
+type evidence struct {
+	receipt   *freeeapigen.Receipt
+	issueDate string
+	amount    int64
+	partner   string
+	// fileName は、出力先でのファイル名です。
+	fileName string
+}
+
+// newEvidence は、証憑の検索項目を取り出します。
+// 発行日・金額・発行元のいずれかが設定されていない場合は ok に false を返します。
+func newEvidence(r *freeeapigen.Receipt) (ev evidence, ok bool) {
+	ev.receipt = r
+	m := r.ReceiptMetadatum
+	if m == nil {
+		return ev, false
+	}
+	ev.issueDate = deref(m.IssueDate, "")
+	ev.partner = strings.TrimSpace(deref(m.PartnerName, ""))
+	if m.Amount != nil {
+		ev.amount = *m.Amount
+	}
+	return ev, ev.issueDate != "" && m.Amount != nil && ev.partner != ""
+}
+
+// baseName は、拡張子を除いたファイル名（YYYYMMDD_取引先_金額）を返します。
+func (ev *evidence) baseName() string {
+	partner := truncateBytes(sanitizeFileName(ev.partner), evidencePartnerMaxBytes)
+	return fmt.Sprintf("%s_%s_%d", strings.ReplaceAll(ev.issueDate, "-", ""), partner, ev.amount)
+}
+
+// truncateBytes は、s が n バイトを超える場合に、文字の途中で切らないよう n バイト以内に切り詰めます。
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// sanitizeFileName は、ファイル名に使用できない文字を全角の代替文字に置き換えます。
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\':
+			return '／'
+		case ':':
+			return '：'
+		case '*':
+			return '＊'
+		case '?':
+			return '？'
+		case '"':
+			return '”'
+		case '<':
+			return '＜'
+		case '>':
+			return '＞'
+		case '|':
+			return '｜'
+		}
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// uniqueFileName は、used に含まれない name+ext のファイル名を返します。
+// 同じ名前がすでにある場合は、name_2、name_3 のように番号を付けます。
+func uniqueFileName(used map[string]bool, name, ext string) string {
+	fileName := name + ext
+	for n := 2; used[fileName]; n++ {
+		fileName = fmt.Sprintf("%s_%d%s", name, n, ext)
+	}
+	used[fileName] = true
+	return fileName
+}
+
+// writeEvidenceIndex は、出力した証憑の索引 CSV を書き込みます。
+func writeEvidenceIndex(path string, evidences []evidence) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	defer f.Close()
+	// 表計算ソフトで文字化けしないよう BOM を付ける。
+	if _, err := f.WriteString("\uFEFF"); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"取引年月日", "取引金額", "取引先", "証憑ID", "ファイル名"})
+	for _, ev := range evidences {
+		w.Write([]string{ev.issueDate, strconv.FormatInt(ev.amount, 10), ev.partner, strconv.FormatInt(ev.receipt.Id, 10), ev.fileName})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
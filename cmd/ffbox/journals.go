@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// manualJournalsPageSize は、振替伝票一覧 API の1回あたりの取得件数（API の上限）です。
+const manualJournalsPageSize = 500
+
+var (
+	flagManualJournalsStartIssueDate = &cli.StringFlag{
+		Name:      "start-issue-date",
+		Usage:     "検索する振替伝票の発生日の開始日 (yyyy-mm-dd)。省略時は証憑の発行日の1か月前",
+		Validator: validateDate,
+	}
+	flagManualJournalsEndIssueDate = &cli.StringFlag{
+		Name:      "end-issue-date",
+		Usage:     "検索する振替伝票の発生日の終了日 (yyyy-mm-dd)。省略時は証憑の発行日の1年後",
+		Validator: validateDate,
+	}
+	flagManualJournalsFormat = &cli.StringFlag{
+		Name:  "format",
+		Usage: "出力フォーマット (table, json)",
+		Value: "table",
+	}
+)
+
+var cmdManualJournals = &cli.Command{
+	Category:  "receipts",
+	Name:      "manual-journals",
+	Usage:     "証憑ファイルが添付されている振替伝票の一覧を表示します",
+	ArgsUsage: "<receipt-id>",
+	Description: `指定した証憑ファイルが添付されている振替伝票を検索して表示します。
+
+発生日が検索期間内の振替伝票をすべて取得し、添付されている証憑を調べます。
+検索期間は、省略時は証憑の発行日（未設定の場合は登録日）の1か月前から1年後までです。
+決算整理仕訳など、発生日が証憑の発行日から離れている場合は期間を指定してください。`,
+	Flags: []cli.Flag{
+		flagManualJournalsStartIssueDate,
+		flagManualJournalsEndIssueDate,
+		flagManualJournalsFormat,
+	},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		format := cmd.String(flagManualJournalsFormat.Name)
+		switch format {
+		case "table", "json":
+			// valid
+		default:
+			return fmt.Errorf("format は table か json を指定してください")
+		}
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("証憑IDを1つ指定してください")
+		}
+		receiptID, err := strconv.ParseInt(cmd.Args().First(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid receipt ID: %q", cmd.Args().First())
+		}
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+
+		start := cmd.String(flagManualJournalsStartIssueDate.Name)
+		end := cmd.String(flagManualJournalsEndIssueDate.Name)
+		if start == "" || end == "" {
+			receipt, err := getReceipt(ctx, freeeapiClient, companyID, receiptID)
+			if err != nil {
+				return err
+			}
+			base, err := receiptBaseDate(receipt)
+			if err != nil {
+				return err
+			}
+			if start == "" {
+				start = base.AddDate(0, -1, 0).Format(time.DateOnly)
+			}
+			if end == "" {
+				end = base.AddDate(1, 0, 0).Format(time.DateOnly)
+			}
+		}
+
+		journals, err := findManualJournalsByReceipt(ctx, freeeapiClient, companyID, receiptID, start, end)
+		if err != nil {
+			return err
+		}
+
+		if format == "json" {
+			for _, j := range journals {
+				b, err := json.Marshal(j)
+				if err != nil {
+					return fmt.Errorf("marshal manual journal: %w", err)
+				}
+				fmt.Println(string(b))
+			}
+			return nil
+		}
+		if len(journals) == 0 {
+			fmt.Printf("証憑 %d が添付されている振替伝票は見つかりませんでした（発生日 %s〜%s）\n", receiptID, start, end)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tISSUE DATE\tAMOUNT\tADJUSTMENT\tRECEIPTS")
+		for _, j := range journals {
+			adjustment := "-"
+			if j.Adjustment {
+				adjustment = "yes"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\n", j.Id, j.IssueDate, manualJournalAmount(&j), adjustment, formatIDs(freeeapi.ManualJournalReceiptIDs(&j)))
+		}
+		return w.Flush()
+	},
+}
+
+// findManualJournalsByReceipt は、発生日が start〜end の振替伝票のうち、証憑 receiptID が添付されているものを返します。
+func findManualJournalsByReceipt(ctx context.Context, client *freeeapi.Client, companyID, receiptID int64, start, end string) ([]freeeapigen.ManualJournal, error) {
+	var found []freeeapigen.ManualJournal
+	for offset := int64(0); ; offset += manualJournalsPageSize {
+		resp, err := client.GetManualJournalsWithResponse(ctx, &freeeapigen.GetManualJournalsParams{
+			CompanyId:      companyID,
+			StartIssueDate: &start,
+			EndIssueDate:   &end,
+			Offset:         ptr(offset),
+			Limit:          ptr(int64(manualJournalsPageSize)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get manual journals: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+			return nil, fmt.Errorf("got unexpected response: %s", resp.Status())
+		}
+		for _, j := range resp.JSON200.ManualJournals {
+			if j.ReceiptIds != nil && slices.Contains(*j.ReceiptIds, receiptID) {
+				found = append(found, j)
+			}
+		}
+		if len(resp.JSON200.ManualJournals) < manualJournalsPageSize {
+			return found, nil
+		}
+	}
+}
+
+// receiptBaseDate は、証憑の発行日を返します。発行日が未設定の場合は登録日を返します。
+func receiptBaseDate(r *freeeapigen.Receipt) (time.Time, error) {
+	if m := r.ReceiptMetadatum; m != nil && m.IssueDate != nil && *m.IssueDate != "" {
+		return time.Parse(time.DateOnly, *m.IssueDate)
+	}
+	t, err := time.Parse(time.RFC3339, r.CreatedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse created_at of receipt ID %d: %w", r.Id, err)
+	}
+	return t, nil
+}
+
+// manualJournalAmount は、振替伝票の借方の合計金額を返します。
+func manualJournalAmount(j *freeeapigen.ManualJournal) int64 {
+	var amount int64
+	for _, d := range j.Details {
+		if d.EntrySide == "debit" {
+			amount += d.Amount
+		}
+	}
+	return amount
+}
+
+// validateDate は、日付フラグの値が yyyy-mm-dd 形式であることを確認します。空の場合は何もしません。
+func validateDate(in string) error {
+	if in == "" {
+		return nil
+	}
+	if _, err := time.Parse(time.DateOnly, in); err != nil {
+		return fmt.Errorf("日付は yyyy-mm-dd 形式で指定してください: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/micheam/freee-filebox-ctl/internal/cache"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+const (
+	// dealsPageSize は、取引一覧 API の1回あたりの取得件数（API の上限）です。
+	dealsPageSize = 100
+	// expenseApplicationsPageSize は、経費申請一覧 API の1回あたりの取得件数（API の上限）です。
+	expenseApplicationsPageSize = 500
+
+	// linksCacheTTL は、月ごとの証憑の添付状況をキャッシュする期間です。
+	linksCacheTTL = time.Hour
+	// linksMonthsBefore と linksMonthsAfter は、証憑の発行日の月から前後何か月を検索するかです。
+	linksMonthsBefore = 1
+	linksMonthsAfter  = 3
+)
+
+// 証憑を添付している書類の種類です。表の TYPE 列と JSON の type に出力します。
+const (
+	linkTypeDeal               = "deal"
+	linkTypeManualJournal      = "manual_journal"
+	linkTypeExpenseApplication = "expense_application"
+)
+
+// receiptLink は、証憑を添付している取引・振替伝票・経費申請です。
+type receiptLink struct {
+	Type       string  `json:"type"`
+	ID         int64   `json:"id"`
+	IssueDate  string  `json:"issue_date"`
+	Amount     int64   `json:"amount"`
+	Title      string  `json:"title,omitempty"`
+	ReceiptIDs []int64 `json:"receipt_ids"`
+}
+
+// linkFinder は、証憑を添付している書類を月ごとに取得し、キャッシュを介して検索します。
+type linkFinder struct {
+	client    *freeeapi.Client
+	companyID int64
+	// store が nil の場合はキャッシュを使用しません。
+	store *cache.Store
+	// refresh が true の場合は、キャッシュを読まずに取得し直します。
+	refresh bool
+}
+
+// newLinkFinder は、ユーザーのキャッシュディレクトリを使用する linkFinder を返します。
+func newLinkFinder(client *freeeapi.Client, companyID int64, refresh bool) *linkFinder {
+	return &linkFinder{client: client, companyID: companyID, store: newCacheStore(), refresh: refresh}
+}
+
+// find は、証憑の発行日（未設定の場合は登録日）の前後の期間で、証憑が添付されている書類を返します。
+// 検索した期間も返します。
+func (f *linkFinder) find(ctx context.Context, r *freeeapigen.Receipt) (links []receiptLink, start, end string, err error) {
+	base, err := receiptBaseDate(r)
+	if err != nil {
+		return nil, "", "", err
+	}
+	first := time.Date(base.Year(), base.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := -linksMonthsBefore; i <= linksMonthsAfter; i++ {
+		month := first.AddDate(0, i, 0)
+		monthly, err := f.month(ctx, month)
+		if err != nil {
+			return nil, "", "", err
+		}
+		for _, l := range monthly {
+			if slices.Contains(l.ReceiptIDs, r.Id) {
+				links = append(links, l)
+			}
+		}
+	}
+	start = first.AddDate(0, -linksMonthsBefore, 0).Format(time.DateOnly)
+	end = first.AddDate(0, linksMonthsAfter+1, -1).Format(time.DateOnly)
+	return links, start, end, nil
+}
+
+// month は、発生日（経費申請は申請日）が month の月の書類のうち、証憑が添付されているものを返します。
+func (f *linkFinder) month(ctx context.Context, month time.Time) ([]receiptLink, error) {
+	key := linksCachePrefix(f.companyID) + "/" + month.Format("2006-01")
+	var links []receiptLink
+	if f.store != nil && !f.refresh {
+		ok, err := f.store.Get(key, linksCacheTTL, &links)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "キャッシュを読み込めませんでした: %v\n", err)
+		} else if ok {
+			return links, nil
+		}
+	}
+
+	start := month.Format(time.DateOnly)
+	end := month.AddDate(0, 1, -1).Format(time.DateOnly)
+	links = []receiptLink{}
+	for _, list := range []func(context.Context, string, string) ([]receiptLink, error){
+		f.listDeals,
+		f.listManualJournals,
+		f.listExpenseApplications,
+	} {
+		l, err := list(ctx, start, end)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l...)
+	}
+
+	if f.store != nil {
+		if err := f.store.Put(key, links); err != nil {
+			fmt.Fprintf(os.Stderr, "キャッシュを保存できませんでした: %v\n", err)
+		}
+	}
+	return links, nil
+}
+
+// linksCachePrefix は、事業所の月ごとの証憑の添付状況のキャッシュのキーの接頭辞です。
+func linksCachePrefix(companyID int64) string {
+	return fmt.Sprintf("links/%d", companyID)
+}
+
+// invalidateReceiptLinks は、事業所の証憑の添付状況のキャッシュを削除します。
+// 証憑の添付先を変更したあとに呼び出し、show --links にすぐに反映されるようにします。
+// 書類の発生日を変更した場合などにも対応できるよう、月を限定せずに削除します。
+func invalidateReceiptLinks(companyID int64) {
+	store := newCacheStore()
+	if store == nil {
+		return
+	}
+	if err := store.DeletePrefix(linksCachePrefix(companyID)); err != nil {
+		fmt.Fprintf(os.Stderr, "キャッシュを削除できませんでした: %v\n", err)
+	}
+}
+
+// listDeals は、発生日が start〜end の取引のうち、証憑が添付されているものを返します。
+func (f *linkFinder) listDeals(ctx context.Context, start, end string) ([]receiptLink, error) {
+	var links []receiptLink
+	for offset := int64(0); ; offset += dealsPageSize {
+		resp, err := f.client.GetDealsWithResponse(ctx, &freeeapigen.GetDealsParams{
+			CompanyId:      f.companyID,
+			StartIssueDate: &start,
+			EndIssueDate:   &end,
+			Offset:         ptr(offset),
+			Limit:          ptr(int64(dealsPageSize)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get deals: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+			return nil, fmt.Errorf("got unexpected response: %s", resp.Status())
+		}
+		for _, d := range resp.JSON200.Deals {
+			if ids := freeeapi.DealReceiptIDs(&d); len(ids) > 0 {
+				links = append(links, receiptLink{Type: linkTypeDeal, ID: d.Id, IssueDate: d.IssueDate, Amount: d.Amount, ReceiptIDs: ids})
+			}
+		}
+		if len(resp.JSON200.Deals) < dealsPageSize {
+			return links, nil
+		}
+	}
+}
+
+// listManualJournals は、発生日が start〜end の振替伝票のうち、証憑が添付されているものを返します。
+func (f *linkFinder) listManualJournals(ctx context.Context, start, end string) ([]receiptLink, error) {
+	var links []receiptLink
+	for offset := int64(0); ; offset += manualJournalsPageSize {
+		resp, err := f.client.GetManualJournalsWithResponse(ctx, &freeeapigen.GetManualJournalsParams{
+			CompanyId:      f.companyID,
+			StartIssueDate: &start,
+			EndIssueDate:   &end,
+			Offset:         ptr(offset),
+			Limit:          ptr(int64(manualJournalsPageSize)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get manual journals: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+			return nil, fmt.Errorf("got unexpected response: %s", resp.Status())
+		}
+		for _, j := range resp.JSON200.ManualJournals {
+			if ids := freeeapi.ManualJournalReceiptIDs(&j); len(ids) > 0 {
+				links = append(links, receiptLink{Type: linkTypeManualJournal, ID: j.Id, IssueDate: j.IssueDate, Amount: manualJournalAmount(&j), ReceiptIDs: ids})
+			}
+		}
+		if len(resp.JSON200.ManualJournals) < manualJournalsPageSize {
+			return links, nil
+		}
+	}
+}
+
+// listExpenseApplications は、申請日が start〜end の経費申請のうち、証憑が添付されているものを返します。
+// 申請行の証憑と補足資料の両方を対象とします。
+func (f *linkFinder) listExpenseApplications(ctx context.Context, start, end string) ([]receiptLink, error) {
+	var links []receiptLink
+	for offset := int64(0); ; offset += expenseApplicationsPageSize {
+		resp, err := f.client.GetExpenseApplicationsWithResponse(ctx, &freeeapigen.GetExpenseApplicationsParams{
+			CompanyId:      f.companyID,
+			StartIssueDate: &start,
+			EndIssueDate:   &end,
+			Offset:         ptr(offset),
+			Limit:          ptr(int64(expenseApplicationsPageSize)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get expense applications: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+			return nil, fmt.Errorf("got unexpected response: %s", resp.Status())
+		}
+		for _, a := range resp.JSON200.ExpenseApplications {
+			var ids []int64
+			if a.PurchaseLines != nil {
+				for _, line := range *a.PurchaseLines {
+					if line.ReceiptId != nil {
+						ids = freeeapi.AddIDs(ids, []int64{*line.ReceiptId})
+					}
+					if line.SubReceiptIds != nil {
+						ids = freeeapi.AddIDs(ids, *line.SubReceiptIds)
+					}
+				}
+			}
+			if len(ids) > 0 {
+				links = append(links, receiptLink{Type: linkTypeExpenseApplication, ID: a.Id, IssueDate: a.IssueDate, Amount: deref(a.TotalAmount, 0), Title: a.Title, ReceiptIDs: ids})
+			}
+		}
+		if len(resp.JSON200.ExpenseApplications) < expenseApplicationsPageSize {
+			return links, nil
+		}
+	}
+}
+
+// printReceiptLinks は、証憑を添付している書類を表形式で出力します。
+func printReceiptLinks(w io.Writer, receiptID int64, links []receiptLink, start, end string) error {
+	if len(links) == 0 {
+		_, err := fmt.Fprintf(w, "証憑 %d が添付されている取引・振替伝票・経費申請は見つかりませんでした（%s〜%s）\n", receiptID, start, end)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tID\tISSUE DATE\tAMOUNT\tTITLE")
+	for _, l := range links {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\n", l.Type, l.ID, l.IssueDate, l.Amount, l.Title)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/config"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+	"github.com/micheam/freee-filebox-ctl/internal/invoice"
+)
+
+// lint の規則名です。
+const (
+	lintRuleInvalidInvoiceRegistrationNumber = "invalid_invoice_registration_number"
+	lintRuleMissingInvoiceRegistrationNumber = "missing_invoice_registration_number"
+	lintRuleUnselectedQualifiedInvoice       = "unselected_qualified_invoice"
+	lintRuleMissingIssueDate                 = "missing_issue_date"
+	lintRuleMissingPartnerName               = "missing_partner_name"
+	lintRuleMissingAmount                    = "missing_amount"
+	lintRuleIssueDateOutsideFiscalYear       = "issue_date_outside_fiscal_year"
+	lintRuleMissingDocumentType              = "missing_document_type"
+)
+
+var (
+	flagLintStartDate = &cli.StringFlag{
+		Name:      "start-date",
+		Usage:     "対象期間の開始日（証憑の登録日） (yyyy-mm-dd)",
+		Value:     time.Now().AddDate(0, 0, -30).Format(time.DateOnly),
+		Validator: validateDate,
+	}
+	flagLintEndDate = &cli.StringFlag{
+		Name:      "end-date",
+		Usage:     "対象期間の終了日（証憑の登録日） (yyyy-mm-dd)",
+		Value:     time.Now().Format(time.DateOnly),
+		Validator: validateDate,
+	}
+	flagLintQualifiedInvoiceThreshold = &cli.Int64Flag{
+		Name:  "qualified-invoice-threshold",
+		Usage: "適格請求書等が未選択の証憑を問題とする金額（この金額以上。省略時は設定ファイルの lint.qualified_invoice_threshold）",
+	}
+	flagLintFormat = &cli.StringFlag{
+		Name:  "format",
+		Usage: "出力フォーマット (table, json)",
+		Value: "table",
+	}
+)
+
+var cmdLint = &cli.Command{
+	Category: "receipts",
+	Name:     "lint",
+	Usage:    "証憑ファイルの登録内容の問題を検出します",
+	Description: `登録日が対象期間内の証憑を取得し、適格請求書等（インボイス）の要件などに照らして
+登録内容の問題を一覧表示します。
+問題が見つかった場合は終了コード 1 で終了するため、月次の締め処理の確認に利用できます。
+
+検出する問題:
+  invalid_invoice_registration_number  登録番号の形式（T と13桁の数字）またはチェックデジットが不正
+  missing_invoice_registration_number  適格請求書等に該当するが、登録番号がない
+  unselected_qualified_invoice         金額が --qualified-invoice-threshold 以上で、適格請求書等が未選択
+  missing_issue_date                   発行日がない
+  missing_partner_name                 発行元がない
+  missing_amount                       金額がない
+  issue_date_outside_fiscal_year       発行日が、事業所のどの会計年度の期間にも含まれない（入力誤りの可能性）
+  missing_document_type                書類の種類が未設定
+
+無視・削除された証憑は対象外です。
+
+  ffbox lint --start-date 2025-04-01 --end-date 2025-04-30
+  ffbox lint --qualified-invoice-threshold 30000 --format json`,
+	Flags: []cli.Flag{
+		flagLintStartDate,
+		flagLintEndDate,
+		flagLintQualifiedInvoiceThreshold,
+		flagLintFormat,
+	},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		format := cmd.String(flagLintFormat.Name)
+		switch format {
+		case "table", "json":
+			// valid
+		default:
+			return fmt.Errorf("format は table か json を指定してください")
+		}
+		start, err := time.Parse(time.DateOnly, cmd.String(flagLintStartDate.Name))
+		if err != nil {
+			return fmt.Errorf("parse --start-date: %w", err)
+		}
+		end, err := time.Parse(time.DateOnly, cmd.String(flagLintEndDate.Name))
+		if err != nil {
+			return fmt.Errorf("parse --end-date: %w", err)
+		}
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+		l := &receiptLinter{qualifiedInvoiceThreshold: config.FromContext(ctx).Lint.QualifiedInvoiceThreshold}
+		if cmd.IsSet(flagLintQualifiedInvoiceThreshold.Name) {
+			l.qualifiedInvoiceThreshold = cmd.Int64(flagLintQualifiedInvoiceThreshold.Name)
+		}
+		if l.fiscalYears, err = getFiscalYears(ctx, freeeapiClient, companyID); err != nil {
+			return err
+		}
+		receipts, err := listReceipts(ctx, freeeapiClient, companyID, start, end, nil)
+		if err != nil {
+			return err
+		}
+
+		var issues []lintIssue
+		checked := 0
+		for _, r := range receipts {
+			if r.Status != freeeapigen.ReceiptStatusConfirmed {
+				continue
+			}
+			checked++
+			issues = append(issues, l.lint(&r)...)
+		}
+
+		if format == "json" {
+			for _, issue := range issues {
+				b, err := json.Marshal(issue)
+				if err != nil {
+					return fmt.Errorf("marshal lint issue: %w", err)
+				}
+				fmt.Println(string(b))
+			}
+		} else if len(issues) == 0 {
+			fmt.Printf("問題は見つかりませんでした（証憑 %d 件）\n", checked)
+		} else if err := printLintIssues(os.Stdout, issues); err != nil {
+			return err
+		}
+		if len(issues) > 0 {
+			return fmt.Errorf("証憑 %d 件のうち、%d 件の問題が見つかりました", checked, len(issues))
+		}
+		return nil
+	},
+}
+
+// lintIssue は、lint で検出した証憑の問題です。
+type lintIssue struct {
+	ReceiptID int64  `json:"receipt_id"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+}
+
+// receiptLinter は、証憑の登録内容を検査します。
+type receiptLinter struct {
+	// qualifiedInvoiceThreshold は、適格請求書等が未選択の証憑を問題とする金額です。
+	qualifiedInvoiceThreshold int64
+	// fiscalYears は、事業所の会計年度です。
+	fiscalYears []fiscalYear
+}
+
+// fiscalYear は、会計年度の期首日と期末日 (yyyy-mm-dd) です。
+type fiscalYear struct {
+	start, end string
+}
+
+// lint は、証憑の登録内容の問題を返します。
+func (l *receiptLinter) lint(r *freeeapigen.Receipt) []lintIssue {
+	var issues []lintIssue
+	add := func(rule, format string, args ...any) {
+		issues = append(issues, lintIssue{r.Id, rule, fmt.Sprintf(format, args...)})
+	}
+
+	number := deref(r.InvoiceRegistrationNumber, "")
+	if number != "" {
+		if err := invoice.ValidateRegistrationNumber(number); err != nil {
+			add(lintRuleInvalidInvoiceRegistrationNumber, "%s", describeInvoiceRegistrationNumberError(number, err))
+		}
+	}
+	var amount *int64
+	var issueDate, partner string
+	if m := r.ReceiptMetadatum; m != nil {
+		amount = m.Amount
+		issueDate = deref(m.IssueDate, "")
+		partner = deref(m.PartnerName, "")
+	}
+	switch deref(r.QualifiedInvoice, freeeapigen.ReceiptQualifiedInvoiceUnselected) {
+	case freeeapigen.ReceiptQualifiedInvoiceQualified:
+		if number == "" {
+			add(lintRuleMissingInvoiceRegistrationNumber, "適格請求書等に該当しますが、登録番号が設定されていません")
+		}
+	case freeeapigen.ReceiptQualifiedInvoiceUnselected:
+		if amount != nil && *amount >= l.qualifiedInvoiceThreshold {
+			add(lintRuleUnselectedQualifiedInvoice, "金額が %d 円ですが、適格請求書等が選択されていません", *amount)
+		}
+	}
+	if issueDate == "" {
+		add(lintRuleMissingIssueDate, "発行日が設定されていません")
+	}
+	if partner == "" {
+		add(lintRuleMissingPartnerName, "発行元が設定されていません")
+	}
+	if amount == nil {
+		add(lintRuleMissingAmount, "金額が設定されていません")
+	}
+	// 前年度末に発行され翌年度に登録された証憑もあるため、登録日の年度とは比較せず、
+	// どの会計年度にも含まれない発行日だけを問題とする。
+	if issueDate != "" && len(l.fiscalYears) > 0 && !l.inFiscalYears(issueDate) {
+		first, last := l.fiscalYearRange()
+		add(lintRuleIssueDateOutsideFiscalYear, "発行日 %s が、事業所の会計年度（%s〜%s）のいずれにも含まれません", issueDate, first, last)
+	}
+	if r.DocumentType == nil {
+		add(lintRuleMissingDocumentType, "書類の種類が設定されていません")
+	}
+	return issues
+}
+
+// inFiscalYears は、日付 date (yyyy-mm-dd) が事業所のいずれかの会計年度に含まれるかを返します。
+func (l *receiptLinter) inFiscalYears(date string) bool {
+	for _, fy := range l.fiscalYears {
+		if fy.start <= date && date <= fy.end {
+			return true
+		}
+	}
+	return false
+}
+
+// fiscalYearRange は、事業所の会計年度全体の最初の期首日と最後の期末日を返します。
+func (l *receiptLinter) fiscalYearRange() (first, last string) {
+	for _, fy := range l.fiscalYears {
+		if first == "" || fy.start < first {
+			first = fy.start
+		}
+		if last == "" || fy.end > last {
+			last = fy.end
+		}
+	}
+	return first, last
+}
+
+// getFiscalYears は、事業所の会計年度を取得します。
+func getFiscalYears(ctx context.Context, client *freeeapi.Client, companyID int64) ([]fiscalYear, error) {
+	resp, err := client.GetCompanyWithResponse(ctx, companyID, &freeeapigen.GetCompanyParams{})
+	if err != nil {
+		return nil, fmt.Errorf("get company ID %d: %w", companyID, err)
+	}
+	if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+		return nil, fmt.Errorf("got unexpected response for company ID %d: %s", companyID, resp.Status())
+	}
+	var fiscalYears []fiscalYear
+	for _, fy := range resp.JSON200.Company.FiscalYears {
+		if fy.StartDate != nil && fy.EndDate != nil {
+			fiscalYears = append(fiscalYears, fiscalYear{*fy.StartDate, *fy.EndDate})
+		}
+	}
+	return fiscalYears, nil
+}
+
+// printLintIssues は、lint で検出した問題を表形式で出力します。
+func printLintIssues(w io.Writer, issues []lintIssue) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RECEIPT\tRULE\tMESSAGE")
+	for _, issue := range issues {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", issue.ReceiptID, issue.Rule, issue.Message)
+	}
+	return tw.Flush()
+}
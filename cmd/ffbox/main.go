@@ -3,15 +3,19 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/urfave/cli/v3"
 
 	"github.com/micheam/freee-filebox-ctl/internal/config"
 	freeeapi "github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
 
 	oauth2kit "github.com/micheam/go-oauth2kit"
+	"golang.org/x/oauth2"
 )
 
 // version は、ビルド時に ldflags 経由で設定されます。
@@ -27,14 +31,37 @@ var app = &cli.Command{
 	Flags: []cli.Flag{
 		flagOauth2ClientID,
 		flagOauth2ClientSecret,
+		flagAccessToken,
 		flagCompanyID,
+		flagProfile,
+		flagConfig,
+		flagRecord,
+		flagReplay,
+		flagDebug,
+		flagDebugBody,
 	},
+	Before: setupConfigPath,
 	Commands: []*cli.Command{
 		cmdReceiptsList,
 		cmdReceiptShow,
 		cmdReceiptUpload,
+		cmdReceiptUpdate,
+		cmdAttach,
+		cmdDetach,
+		cmdManualJournals,
+		cmdMatch,
+		cmdSync,
+		cmdSearch,
+		cmdLint,
+		cmdClassify,
+		cmdWatch,
 
-		cmdCompaniesList,
+		cmdDeal,
+		cmdExpense,
+		cmdPaymentRequest,
+		cmdExport,
+		cmdAudit,
+		cmdCompanies,
 		{
 			Name:     "config",
 			Usage:    "このアプリケーションの設定を管理します",
@@ -57,12 +84,58 @@ var (
 		Usage:   "OAuth2 Client Secret",
 		Sources: cli.EnvVars("FREEEAPI_OAUTH2_CLIENT_SECRET"),
 	}
-	// flagCompanyID は、freee 事業所IDを指定するためのフラグです。
+	// flagAccessToken は、OAuth2 の認可フローを経ずに使用するアクセストークンを指定するためのフラグです。
+	// 主に ffbox-mock などのモックサーバーや CI での利用を想定しています。
+	flagAccessToken = &cli.StringFlag{
+		Name:    "access-token",
+		Usage:   "OAuth2 アクセストークン（指定時は認可フローを省略）",
+		Sources: cli.EnvVars("FFBOX_ACCESS_TOKEN"),
+	}
+	// flagCompanyID は、freee 事業所を指定するためのフラグです。
+	// 事業所ID のほか、事業所名・事業所名（カナ）でも指定できます。
 	flagCompanyID = &cli.StringFlag{
-		Name:    "company-id",
-		Usage:   "freee 事業所ID",
+		Name:    "company",
+		Aliases: []string{"company-id"},
+		Usage:   "freee 事業所ID または事業所名",
 		Sources: cli.EnvVars("FREEEAPI_COMPANY_ID"),
 	}
+	// flagProfile は、設定ファイルに定義されたプロファイルを選択するためのフラグです。
+	flagProfile = &cli.StringFlag{
+		Name:    "profile",
+		Usage:   "使用するプロファイル名",
+		Sources: cli.EnvVars("FFBOX_PROFILE"),
+	}
+	// flagConfig は、設定ファイルのパスを明示的に指定するためのフラグです。
+	flagConfig = &cli.StringFlag{
+		Name:    "config",
+		Usage:   "設定ファイルのパス",
+		Sources: cli.EnvVars("FFBOX_CONFIG"),
+	}
+	// flagRecord は、API とのやり取りをカセットファイルに記録するためのフラグです。
+	flagRecord = &cli.StringFlag{
+		Name:      "record",
+		Usage:     "API リクエスト・レスポンスを指定したファイルに記録します",
+		TakesFile: true,
+	}
+	// flagReplay は、カセットファイルに記録されたレスポンスを再生するためのフラグです。
+	// 指定時はネットワークにアクセスしません。
+	flagReplay = &cli.StringFlag{
+		Name:      "replay",
+		Usage:     "--record で記録したファイルからレスポンスを再生します（ネットワークにアクセスしません）",
+		TakesFile: true,
+	}
+	// flagDebug は、API リクエストのデバッグログを標準エラー出力に書き出すためのフラグです。
+	flagDebug = &cli.BoolFlag{
+		Name:    "debug",
+		Usage:   "API リクエストのデバッグログを標準エラー出力に表示します",
+		Sources: cli.EnvVars("FFBOX_DEBUG"),
+	}
+	// flagDebugBody は、デバッグログにリクエスト・レスポンスのボディを含めるためのフラグです。
+	flagDebugBody = &cli.BoolFlag{
+		Name:    "debug-body",
+		Usage:   "デバッグログにリクエスト・レスポンスのボディを含めます（--debug を含意します）",
+		Sources: cli.EnvVars("FFBOX_DEBUG_BODY"),
+	}
 )
 
 func main() {
@@ -94,8 +167,25 @@ func deref[T any](p *T, defaultValue T) T {
 	return defaultValue
 }
 
+// splitFields は、--fields フラグに指定されたカンマ区切りのフィールド名を分割します。
+// 空文字列の場合は nil を返します。
+func splitFields(s string) []string {
+	if s == "" {
+		return nil
+	}
+	fields := strings.Split(s, ",")
+	// Trim whitespace from each field
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
 // prepareFreeeAPIClient は、OAuth2 認証を使用して freee API クライアントを初期化します。
 //
+// --record が指定されている場合は API とのやり取りをカセットファイルに記録し、
+// --replay が指定されている場合は認証やネットワークアクセスを行わずにカセットファイルから応答します。
+//
 // 実行時に context.Context から Application Config が事前に読み込まれていることを前提としています。
 // 読み込まれていない場合、panic します。
 func prepareFreeeAPIClient(ctx context.Context, cmd *cli.Command) (*freeeapi.Client, error) {
@@ -103,23 +193,76 @@ func prepareFreeeAPIClient(ctx context.Context, cmd *cli.Command) (*freeeapi.Cli
 	if appConfig == nil {
 		panic("app config is not set in context")
 	}
-	if !cmd.IsSet(flagOauth2ClientID.Name) || !cmd.IsSet(flagOauth2ClientSecret.Name) {
-		return nil, fmt.Errorf("client-id and client-secret must be set")
+	opts := []freeeapigen.ClientOption{freeeapigen.WithBaseURL(appConfig.Freee.APIEndpoint)}
+
+	// デバッグログは、OAuth2 のトークン取得も含むすべての通信を対象とするため、
+	// OAuth2 クライアントが使用する下位の HTTP クライアントに設定します。
+	var debugTransport *freeeapi.DebugTransport
+	if cmd.Bool(flagDebug.Name) || cmd.Bool(flagDebugBody.Name) {
+		logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		debugTransport = &freeeapi.DebugTransport{Logger: logger, Bodies: cmd.Bool(flagDebugBody.Name)}
+		if base, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+			debugTransport.Base = base.Transport
+		}
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: debugTransport})
+		opts = append(opts, freeeapigen.WithRequestEditorFn(freeeapi.DebugRequestEditor(logger)))
+	}
+
+	recordPath, replayPath := cmd.String(flagRecord.Name), cmd.String(flagReplay.Name)
+	if recordPath != "" && replayPath != "" {
+		return nil, fmt.Errorf("--record と --replay は同時に指定できません")
+	}
+	if replayPath != "" {
+		replayer, err := freeeapi.LoadReplayer(replayPath)
+		if err != nil {
+			return nil, err
+		}
+		var transport http.RoundTripper = replayer
+		if debugTransport != nil {
+			debugTransport.Base = replayer
+			transport = debugTransport
+		}
+		return freeeapi.NewClient(&http.Client{Transport: transport}, opts...)
 	}
 
-	tokenFilePath := appConfig.OAuth2.TokenFile
-	if !filepath.IsAbs(tokenFilePath) {
-		dir := filepath.Dir(config.ConfigPath())
-		tokenFilePath = filepath.Join(dir, tokenFilePath)
+	httpClient, err := newOAuth2HTTPClient(ctx, cmd, appConfig)
+	if err != nil {
+		return nil, err
+	}
+	if recordPath != "" {
+		// 認可ヘッダーが付与される前のリクエストを記録するため、OAuth2 の Transport を包みます。
+		httpClient.Transport = freeeapi.NewRecorder(recordPath, httpClient.Transport)
+	}
+	return freeeapi.NewClient(httpClient, opts...)
+}
+
+// newOAuth2HTTPClient は、アクセストークンを付与してリクエストを送信する HTTP クライアントを生成します。
+//
+// --access-token が指定されている場合はそのトークンを使用し、
+// そうでなければ OAuth2 の認可フロー（保存済みトークンがあればそれを使用）を経てトークンを取得します。
+func newOAuth2HTTPClient(ctx context.Context, cmd *cli.Command, appConfig *config.Config) (*http.Client, error) {
+	if accessToken := cmd.String(flagAccessToken.Name); accessToken != "" {
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken})
+		return oauth2.NewClient(ctx, ts), nil
+	}
+
+	clientID, clientSecret, err := detectOAuth2Credentials(cmd, appConfig)
+	if err != nil {
+		return nil, err
 	}
 
+	tokenFilePath := appConfig.ResolvePath("oauth2.token_file", appConfig.OAuth2.TokenFile)
+
 	oauth2Config := oauth2kit.Config{
-		ClientID:     cmd.String(flagOauth2ClientID.Name),
-		ClientSecret: cmd.String(flagOauth2ClientSecret.Name),
-		Endpoint:     freeeapi.Oauth2Endpoint(),
-		Scopes:       []string{"read", "write"},
-		TokenFile:    tokenFilePath,
-		LocalAddr:    appConfig.OAuth2.LocalAddr,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  appConfig.OAuth2.AuthURL,
+			TokenURL: appConfig.OAuth2.TokenURL,
+		},
+		Scopes:    []string{"read", "write"},
+		TokenFile: tokenFilePath,
+		LocalAddr: appConfig.OAuth2.LocalAddr,
 	}
 	oauth2Mngr := &oauth2kit.Manager{
 		Config: oauth2Config,
@@ -129,5 +272,27 @@ func prepareFreeeAPIClient(ctx context.Context, cmd *cli.Command) (*freeeapi.Cli
 	if err != nil {
 		return nil, fmt.Errorf("create oauth2 client: %w", err)
 	}
-	return freeeapi.NewClient(httpClient)
+	return httpClient, nil
+}
+
+// detectOAuth2Credentials は、OAuth2 クライアントID・シークレットを優先度に従って検出します。
+//
+//  1. コマンドライン引数
+//  2. 環境変数
+//  3. 選択中のプロファイルが参照する環境変数
+func detectOAuth2Credentials(cmd *cli.Command, cfg *config.Config) (clientID, clientSecret string, err error) {
+	clientID = cmd.String(flagOauth2ClientID.Name)
+	clientSecret = cmd.String(flagOauth2ClientSecret.Name)
+	if _, profile, ok := cfg.ActiveProfile(); ok {
+		if clientID == "" && profile.ClientIDEnv != "" {
+			clientID = os.Getenv(profile.ClientIDEnv)
+		}
+		if clientSecret == "" && profile.ClientSecretEnv != "" {
+			clientSecret = os.Getenv(profile.ClientSecretEnv)
+		}
+	}
+	if clientID == "" || clientSecret == "" {
+		return "", "", fmt.Errorf("client-id and client-secret must be set")
+	}
+	return clientID, clientSecret, nil
 }
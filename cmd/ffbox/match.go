@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+	"github.com/micheam/freee-filebox-ctl/internal/match"
+)
+
+const (
+	// receiptsPageSize は、証憑一覧 API の1回あたりの取得件数（API の上限）です。
+	receiptsPageSize = 3000
+	// walletTxnsPageSize は、明細一覧 API の1回あたりの取得件数（API の上限）です。
+	walletTxnsPageSize = 100
+	// walletTxnStatusUnsettled は、消込待ちの明細のステータスです。
+	walletTxnStatusUnsettled = 1
+)
+
+var (
+	flagMatchStartDate = &cli.StringFlag{
+		Name:      "start-date",
+		Usage:     "対象期間の開始日 (yyyy-mm-dd)",
+		Value:     time.Now().AddDate(0, 0, -30).Format(time.DateOnly),
+		Validator: validateDate,
+	}
+	flagMatchEndDate = &cli.StringFlag{
+		Name:      "end-date",
+		Usage:     "対象期間の終了日 (yyyy-mm-dd)",
+		Value:     time.Now().Format(time.DateOnly),
+		Validator: validateDate,
+	}
+	flagMatchMaxDays = &cli.UintFlag{
+		Name:  "max-days",
+		Usage: "証憑の発行日と明細の取引日の差の許容日数",
+		Value: 7,
+	}
+	flagMatchMinScore = &cli.Float64Flag{
+		Name:  "min-score",
+		Usage: "表示する候補の最低スコア（0〜1）",
+		Value: 0.5,
+	}
+	flagMatchFormat = &cli.StringFlag{
+		Name:  "format",
+		Usage: "出力フォーマット (table, json)",
+		Value: "table",
+	}
+	flagMatchCreateDeals = &cli.BoolFlag{
+		Name:  "create-deals",
+		Usage: "候補ごとに確認のうえ、証憑と明細から決済済みの取引を作成します",
+	}
+	flagMatchYes = &cli.BoolFlag{
+		Name:    "yes",
+		Aliases: []string{"y"},
+		Usage:   "--create-deals で確認せずにすべての候補から取引を作成します",
+	}
+	flagMatchAccountItemID = &cli.Int64Flag{
+		Name:  "account-item-id",
+		Usage: "作成する取引の勘定科目ID（省略時は設定ファイルの deal.account_item_id）",
+	}
+	flagMatchTaxCode = &cli.Int64Flag{
+		Name:  "tax-code",
+		Usage: "作成する取引の税区分コード（省略時は設定ファイルの deal.tax_code）",
+	}
+)
+
+var cmdMatch = &cli.Command{
+	Category: "receipts",
+	Name:     "match",
+	Usage:    "取引未登録の証憑と口座明細の組み合わせ候補を表示します",
+	Description: `取引が登録されていない証憑（category=without_deal）と、消込待ちの出金明細を突き合わせ、
+同じ支払いを記録していると思われる組み合わせを、スコアの高い順に表示します。
+
+スコアは次の3つの観点の加重平均（0〜1）です。
+  金額  (50%)  金額が一致すれば 1、差が 1% 以内なら 0.5
+  日付  (30%)  証憑の発行日と明細の取引日が近いほど高く、--max-days 日以上離れると 0
+  名前  (20%)  証憑の発行元と明細の取引内容の類似度
+
+証憑は登録日が対象期間内のもの、明細は取引日が対象期間の前後 --max-days 日以内のものが対象です。
+各証憑・各明細は、最もスコアの高い1つの候補にだけ現れます。
+
+--create-deals を指定すると、候補ごとに確認のうえ、証憑を添付し明細の口座で決済した取引を作成します。
+証憑と明細の金額が異なる候補からは、取引を作成しません。`,
+	Flags: []cli.Flag{
+		flagMatchStartDate,
+		flagMatchEndDate,
+		flagMatchMaxDays,
+		flagMatchMinScore,
+		flagMatchFormat,
+		flagMatchCreateDeals,
+		flagMatchYes,
+		flagMatchAccountItemID,
+		flagMatchTaxCode,
+	},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		format := cmd.String(flagMatchFormat.Name)
+		switch format {
+		case "table", "json":
+			// valid
+		default:
+			return fmt.Errorf("format は table か json を指定してください")
+		}
+		var opts dealOptions
+		if cmd.Bool(flagMatchCreateDeals.Name) {
+			var err error
+			opts, err = newDealOptions(ctx, cmd.Int64(flagMatchAccountItemID.Name), cmd.Int64(flagMatchTaxCode.Name))
+			if err != nil {
+				return err
+			}
+		}
+		start, err := time.Parse(time.DateOnly, cmd.String(flagMatchStartDate.Name))
+		if err != nil {
+			return fmt.Errorf("start-date: %w", err)
+		}
+		end, err := time.Parse(time.DateOnly, cmd.String(flagMatchEndDate.Name))
+		if err != nil {
+			return fmt.Errorf("end-date: %w", err)
+		}
+		maxDays := int(cmd.Uint(flagMatchMaxDays.Name))
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+
+		receipts, err := listReceiptsWithoutDeal(ctx, freeeapiClient, companyID, start, end)
+		if err != nil {
+			return err
+		}
+		txns, err := listUnsettledWalletTxns(ctx, freeeapiClient, companyID, start.AddDate(0, 0, -maxDays), end.AddDate(0, 0, maxDays))
+		if err != nil {
+			return err
+		}
+
+		byID := make(map[int64]*freeeapigen.Receipt, len(receipts))
+		var mr []match.Receipt
+		for i, r := range receipts {
+			m, ok := matchReceipt(&r)
+			if !ok {
+				continue
+			}
+			byID[r.Id] = &receipts[i]
+			mr = append(mr, m)
+		}
+		txnByID := make(map[int64]*freeeapigen.WalletTxn, len(txns))
+		var mt []match.Txn
+		for i, t := range txns {
+			date, err := time.Parse(time.DateOnly, t.Date)
+			if err != nil {
+				continue
+			}
+			txnByID[t.Id] = &txns[i]
+			mt = append(mt, match.Txn{ID: t.Id, Amount: t.Amount, Date: date, Description: t.Description})
+		}
+		suggestions := match.Suggest(mr, mt, match.Options{
+			MaxDays:  maxDays,
+			MinScore: cmd.Float64(flagMatchMinScore.Name),
+		})
+
+		if format == "json" {
+			for _, s := range suggestions {
+				b, err := json.Marshal(matchSuggestionJSON(s))
+				if err != nil {
+					return fmt.Errorf("marshal suggestion: %w", err)
+				}
+				fmt.Println(string(b))
+			}
+		} else if err := printMatchSuggestions(os.Stdout, suggestions); err != nil {
+			return err
+		}
+
+		if !cmd.Bool(flagMatchCreateDeals.Name) || len(suggestions) == 0 {
+			return nil
+		}
+		in := bufio.NewReader(cmd.Root().Reader)
+		for _, s := range suggestions {
+			// 取引の金額は証憑の金額、決済の金額は明細の金額になるため、一致しない候補からは作成しない。
+			if s.Receipt.Amount != s.Txn.Amount {
+				fmt.Fprintf(os.Stderr, "証憑 %d（%d円）と明細 %d（%d円）は金額が異なるため、取引を作成しません\n",
+					s.Receipt.ID, s.Receipt.Amount, s.Txn.ID, s.Txn.Amount)
+				continue
+			}
+			if !cmd.Bool(flagMatchYes.Name) {
+				ok, err := confirm(in, fmt.Sprintf("証憑 %d と明細 %d から取引を作成しますか？", s.Receipt.ID, s.Txn.ID))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+			params, err := newDealCreateParams(ctx, freeeapiClient, companyID, byID[s.Receipt.ID], opts)
+			if err != nil {
+				return err
+			}
+			if err := setDealPayment(params, txnByID[s.Txn.ID]); err != nil {
+				return err
+			}
+			id, err := createDeal(ctx, freeeapiClient, *params)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "取引 %d を作成しました（証憑 %d、明細 %d）\n", id, s.Receipt.ID, s.Txn.ID)
+		}
+		return nil
+	},
+}
+
+// listReceiptsWithoutDeal は、登録日が start〜end の証憑のうち、取引が登録されていないものを返します。
+func listReceiptsWithoutDeal(ctx context.Context, client *freeeapi.Client, companyID int64, start, end time.Time) ([]freeeapigen.Receipt, error) {
+	return listReceipts(ctx, client, companyID, start, end, ptr(freeeapigen.GetReceiptsParamsCategoryWithoutDeal))
+}
+
+// listReceipts は、登録日が start〜end の証憑のうち、category に該当するものをすべて返します。
+// category が nil の場合は、削除されていないすべての証憑を返します。
+func listReceipts(ctx context.Context, client *freeeapi.Client, companyID int64, start, end time.Time, category *freeeapigen.GetReceiptsParamsCategory) ([]freeeapigen.Receipt, error) {
+	var receipts []freeeapigen.Receipt
+	for offset := int64(0); ; offset += receiptsPageSize {
+		resp, err := client.GetReceiptsWithResponse(ctx, &freeeapigen.GetReceiptsParams{
+			CompanyId: companyID,
+			StartDate: start.Format(time.DateOnly),
+			EndDate:   end.Format(time.DateOnly),
+			Category:  category,
+			Offset:    ptr(offset),
+			Limit:     ptr(int64(receiptsPageSize)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get receipts: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+			return nil, fmt.Errorf("got unexpected response: %s", resp.Status())
+		}
+		receipts = append(receipts, resp.JSON200.Receipts...)
+		if len(resp.JSON200.Receipts) < receiptsPageSize {
+			return receipts, nil
+		}
+	}
+}
+
+// listUnsettledWalletTxns は、取引日が start〜end の消込待ちの出金明細を返します。
+func listUnsettledWalletTxns(ctx context.Context, client *freeeapi.Client, companyID int64, start, end time.Time) ([]freeeapigen.WalletTxn, error) {
+	var txns []freeeapigen.WalletTxn
+	for offset := int64(0); ; offset += walletTxnsPageSize {
+		resp, err := client.GetWalletTxnsWithResponse(ctx, &freeeapigen.GetWalletTxnsParams{
+			CompanyId: companyID,
+			StartDate: ptr(start.Format(time.DateOnly)),
+			EndDate:   ptr(end.Format(time.DateOnly)),
+			EntrySide: ptr(freeeapigen.GetWalletTxnsParamsEntrySide("expense")),
+			Offset:    ptr(offset),
+			Limit:     ptr(int64(walletTxnsPageSize)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get wallet txns: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+			return nil, fmt.Errorf("got unexpected response: %s", resp.Status())
+		}
+		for _, t := range resp.JSON200.WalletTxns {
+			if t.Status == walletTxnStatusUnsettled {
+				txns = append(txns, t)
+			}
+		}
+		if len(resp.JSON200.WalletTxns) < walletTxnsPageSize {
+			return txns, nil
+		}
+	}
+}
+
+// matchReceipt は、証憑を突き合わせ用に変換します。金額または発行日が未設定の証憑は対象外です。
+func matchReceipt(r *freeeapigen.Receipt) (match.Receipt, bool) {
+	m := r.ReceiptMetadatum
+	if m == nil || m.Amount == nil || m.IssueDate == nil {
+		return match.Receipt{}, false
+	}
+	date, err := time.Parse(time.DateOnly, *m.IssueDate)
+	if err != nil {
+		return match.Receipt{}, false
+	}
+	return match.Receipt{ID: r.Id, Amount: *m.Amount, IssueDate: date, PartnerName: deref(m.PartnerName, "")}, true
+}
+
+// printMatchSuggestions は、組み合わせ候補を表形式で出力します。
+func printMatchSuggestions(w io.Writer, suggestions []match.Suggestion) error {
+	if len(suggestions) == 0 {
+		fmt.Fprintln(w, "組み合わせの候補は見つかりませんでした")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCORE\tRECEIPT\tISSUE DATE\tPARTNER\tAMOUNT\tTXN\tDATE\tDESCRIPTION\tAMOUNT")
+	for _, s := range suggestions {
+		fmt.Fprintf(tw, "%.2f\t%d\t%s\t%s\t%d\t%d\t%s\t%s\t%d\n",
+			s.Score,
+			s.Receipt.ID, s.Receipt.IssueDate.Format(time.DateOnly), s.Receipt.PartnerName, s.Receipt.Amount,
+			s.Txn.ID, s.Txn.Date.Format(time.DateOnly), s.Txn.Description, s.Txn.Amount)
+	}
+	return tw.Flush()
+}
+
+// matchSuggestionJSON は、組み合わせ候補を JSON 出力用の値に変換します。
+func matchSuggestionJSON(s match.Suggestion) map[string]any {
+	return map[string]any{
+		"score":        s.Score,
+		"amount_score": s.AmountScore,
+		"date_score":   s.DateScore,
+		"name_score":   s.NameScore,
+		"receipt": map[string]any{
+			"id":           s.Receipt.ID,
+			"issue_date":   s.Receipt.IssueDate.Format(time.DateOnly),
+			"partner_name": s.Receipt.PartnerName,
+			"amount":       s.Receipt.Amount,
+		},
+		"wallet_txn": map[string]any{
+			"id":          s.Txn.ID,
+			"date":        s.Txn.Date.Format(time.DateOnly),
+			"description": s.Txn.Description,
+			"amount":      s.Txn.Amount,
+		},
+	}
+}
+
+// setDealPayment は、明細 t の口座から支払ったものとして、取引に支払行を設定します。
+func setDealPayment(params *freeeapigen.DealCreateParams, t *freeeapigen.WalletTxn) error {
+	// Payments is a pointer to a slice of an anonymous struct, so fill it through JSON.
+	b, err := json.Marshal([]map[string]any{{
+		"amount":               t.Amount,
+		"date":                 t.Date,
+		"from_walletable_id":   t.WalletableId,
+		"from_walletable_type": t.WalletableType,
+	}})
+	if err != nil {
+		return fmt.Errorf("marshal deal payments: %w", err)
+	}
+	if err := json.Unmarshal(b, &params.Payments); err != nil {
+		return fmt.Errorf("unmarshal deal payments: %w", err)
+	}
+	return nil
+}
+
+// confirm は、標準エラー出力に prompt を表示し、y または yes が入力された場合に true を返します。
+func confirm(in *bufio.Reader, prompt string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+	line, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("read answer: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/audit"
+	"github.com/micheam/freee-filebox-ctl/internal/config"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+var cmdPaymentRequest = &cli.Command{
+	Name:   "payment-request",
+	Usage:  "支払依頼を操作します",
+	Before: loadAppConfig,
+	Commands: []*cli.Command{
+		cmdPaymentRequestCreate,
+	},
+}
+
+var (
+	flagPaymentRequestCreateFromReceipt = &cli.Int64Flag{
+		Name:     "from-receipt",
+		Usage:    "支払依頼の作成元とする証憑ID（書類の種類が請求書のもの）",
+		Required: true,
+	}
+	flagPaymentRequestCreateApprovalFlowRouteID = &cli.Int64Flag{
+		Name:     "approval-flow-route-id",
+		Usage:    "申請経路ID",
+		Required: true,
+	}
+	flagPaymentRequestCreateApproverID = &cli.Int64Flag{
+		Name:  "approver-id",
+		Usage: "承認者のユーザーID（「承認者を指定」の経路を使用する場合）",
+	}
+	flagPaymentRequestCreateDueDate = &cli.StringFlag{
+		Name:      "due-date",
+		Usage:     "支払期限 (yyyy-mm-dd)",
+		Validator: validateDate,
+	}
+	flagPaymentRequestCreateTitle = &cli.StringFlag{
+		Name:  "title",
+		Usage: "申請タイトル（省略時は「<発行元> 請求書 <発行日>」）",
+	}
+	flagPaymentRequestCreateAccountItemID = &cli.Int64Flag{
+		Name:  "account-item-id",
+		Usage: "勘定科目ID（省略時は設定ファイルの deal.account_item_id）",
+	}
+	flagPaymentRequestCreateTaxCode = &cli.Int64Flag{
+		Name:  "tax-code",
+		Usage: "税区分コード（省略時は設定ファイルの deal.tax_code）",
+	}
+	flagPaymentRequestCreatePartnerID = &cli.Int64Flag{
+		Name:  "partner-id",
+		Usage: "取引先ID（省略時は証憑の発行元の名前から検索）",
+	}
+	flagPaymentRequestCreateDescription = &cli.StringFlag{
+		Name:  "description",
+		Usage: "支払依頼の備考（省略時は証憑のメモ）",
+	}
+	flagPaymentRequestCreateSubmit = &cli.BoolFlag{
+		Name:  "submit",
+		Usage: "下書きではなく申請中として作成します",
+	}
+	flagPaymentRequestCreateDryRun = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "支払依頼を作成せず、送信する内容を JSON で表示します",
+	}
+)
+
+var cmdPaymentRequestCreate = &cli.Command{
+	Name:      "create",
+	Usage:     "請求書の証憑ファイルから支払依頼を作成します",
+	ArgsUsage: "--from-receipt <receipt-id> --approval-flow-route-id <route-id>",
+	Description: `書類の種類が請求書（invoice）の証憑ファイルから支払依頼を作成し、その証憑を添付します。
+
+発行日・金額・発行元（取引先）・適格請求書等の区分は証憑から設定します。
+作成した支払依頼のIDを表示します。支払依頼は下書きとして作成されます。
+
+  ffbox payment-request create --from-receipt 999999999 --approval-flow-route-id 456 --due-date 2025-12-31`,
+	Flags: []cli.Flag{
+		flagPaymentRequestCreateFromReceipt,
+		flagPaymentRequestCreateApprovalFlowRouteID,
+		flagPaymentRequestCreateApproverID,
+		flagPaymentRequestCreateDueDate,
+		flagPaymentRequestCreateTitle,
+		flagPaymentRequestCreateAccountItemID,
+		flagPaymentRequestCreateTaxCode,
+		flagPaymentRequestCreatePartnerID,
+		flagPaymentRequestCreateDescription,
+		flagPaymentRequestCreateSubmit,
+		flagPaymentRequestCreateDryRun,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+
+		receiptID := cmd.Int64(flagPaymentRequestCreateFromReceipt.Name)
+		receipt, err := getReceipt(ctx, freeeapiClient, companyID, receiptID)
+		if err != nil {
+			return err
+		}
+		if receipt.DocumentType == nil || *receipt.DocumentType != "invoice" {
+			return fmt.Errorf("証憑 %d は書類の種類が請求書（invoice）ではありません", receiptID)
+		}
+		var amount *int64
+		var issueDate, partnerName *string
+		if m := receipt.ReceiptMetadatum; m != nil {
+			amount, issueDate, partnerName = m.Amount, m.IssueDate, m.PartnerName
+		}
+		if issueDate == nil || *issueDate == "" {
+			return fmt.Errorf("証憑 %d に発行日が設定されていません", receiptID)
+		}
+		if amount == nil {
+			return fmt.Errorf("証憑 %d に金額が設定されていません", receiptID)
+		}
+
+		partnerID := cmd.Int64(flagPaymentRequestCreatePartnerID.Name)
+		if partnerID == 0 && partnerName != nil && *partnerName != "" {
+			id, found, err := findPartnerID(ctx, freeeapiClient, companyID, *partnerName)
+			if err != nil {
+				return err
+			}
+			if found {
+				partnerID = id
+			} else {
+				fmt.Fprintf(os.Stderr, "取引先 %q が見つからないため、取引先を設定せずに作成します\n", *partnerName)
+			}
+		}
+
+		title := cmd.String(flagPaymentRequestCreateTitle.Name)
+		if title == "" {
+			title = "請求書 " + *issueDate
+			if partnerName != nil && *partnerName != "" {
+				title = *partnerName + " " + title
+			}
+		}
+		params := freeeapigen.PaymentRequestCreateParams{
+			CompanyId:           companyID,
+			Title:               title,
+			IssueDate:           *issueDate,
+			ApprovalFlowRouteId: cmd.Int64(flagPaymentRequestCreateApprovalFlowRouteID.Name),
+			Draft:               !cmd.Bool(flagPaymentRequestCreateSubmit.Name),
+			ReceiptIds:          &[]int64{receiptID},
+		}
+		if v := cmd.Int64(flagPaymentRequestCreateApproverID.Name); v != 0 {
+			params.ApproverId = &v
+		}
+		if v := cmd.String(flagPaymentRequestCreateDueDate.Name); v != "" {
+			params.PaymentDate = &v
+		}
+		if partnerID != 0 {
+			params.PartnerId = &partnerID
+		}
+		if v := cmd.String(flagPaymentRequestCreateDescription.Name); v != "" {
+			params.Description = &v
+		} else if receipt.Description != nil && *receipt.Description != "" {
+			params.Description = receipt.Description
+		}
+		if receipt.QualifiedInvoice != nil {
+			status := freeeapigen.PaymentRequestCreateParamsQualifiedInvoiceStatusUnspecified
+			switch *receipt.QualifiedInvoice {
+			case freeeapigen.ReceiptQualifiedInvoiceQualified:
+				status = freeeapigen.PaymentRequestCreateParamsQualifiedInvoiceStatusQualified
+			case freeeapigen.ReceiptQualifiedInvoiceNotQualified:
+				status = freeeapigen.PaymentRequestCreateParamsQualifiedInvoiceStatusNotQualified
+			}
+			params.QualifiedInvoiceStatus = &status
+		}
+
+		// PaymentRequestLines is a slice of an anonymous struct, so grow it instead of appending a literal.
+		params.PaymentRequestLines = slices.Grow(params.PaymentRequestLines, 1)[:1]
+		line := &params.PaymentRequestLines[0]
+		line.Amount = *amount
+		line.LineType = ptr(freeeapigen.PaymentRequestCreateParamsPaymentRequestLinesLineTypeDealLine)
+		cfg := config.FromContext(ctx)
+		if v := cmp.Or(cmd.Int64(flagPaymentRequestCreateAccountItemID.Name), cfg.Deal.AccountItemID); v != 0 {
+			line.AccountItemId = &v
+		}
+		if v := cmp.Or(cmd.Int64(flagPaymentRequestCreateTaxCode.Name), cfg.Deal.TaxCode); v != 0 {
+			line.TaxCode = &v
+		}
+
+		if cmd.Bool(flagPaymentRequestCreateDryRun.Name) {
+			b, err := json.MarshalIndent(params, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshal payment request params: %w", err)
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		resp, err := freeeapiClient.CreatePaymentRequestWithResponse(ctx, params)
+		if err != nil {
+			return fmt.Errorf("create payment request: %w", err)
+		}
+		if resp.StatusCode() != http.StatusCreated || resp.JSON201 == nil {
+			return fmt.Errorf("got unexpected response: %s", resp.Status())
+		}
+		id := resp.JSON201.PaymentRequest.Id
+		invalidateReceiptLinks(companyID)
+		entry := audit.Entry{
+			CompanyID:  companyID,
+			ReceiptIDs: []int64{receiptID},
+			Operation:  auditOpPaymentRequestCreate,
+			Target:     fmt.Sprintf("payment_request:%d", id),
+		}
+		if err := recordAudit(ctx, entry, params); err != nil {
+			return fmt.Errorf("支払依頼 %d: %w", id, err)
+		}
+		fmt.Println(id)
+		return nil
+	},
+}
@@ -12,12 +12,15 @@ import (
 	"runtime"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/urfave/cli/v3"
 
+	"github.com/micheam/freee-filebox-ctl/internal/audit"
 	"github.com/micheam/freee-filebox-ctl/internal/formatter"
 	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
 	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+	"github.com/micheam/freee-filebox-ctl/internal/rules"
 )
 
 var (
@@ -82,11 +85,11 @@ var cmdReceiptsList = &cli.Command{
 			return nil
 		}
 
-		companyID, err := detectCompanyID(ctx, cmd)
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
 		if err != nil {
 			return err
 		}
-		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
 		if err != nil {
 			return err
 		}
@@ -105,15 +108,7 @@ var cmdReceiptsList = &cli.Command{
 		}
 
 		// Parse fields
-		var fields []string
-		fieldsStr := cmd.String(cmdReceiptsListFlags_fields.Name)
-		if fieldsStr != "" {
-			fields = strings.Split(fieldsStr, ",")
-			// Trim whitespace from each field
-			for i := range fields {
-				fields[i] = strings.TrimSpace(fields[i])
-			}
-		}
+		fields := splitFields(cmd.String(cmdReceiptsListFlags_fields.Name))
 
 		limit := cmd.Uint(cmdReceiptsListFlags_limit.Name)
 		if limit < 1 || 3_000 < limit {
@@ -137,6 +132,7 @@ var cmdReceiptsList = &cli.Command{
 				fmt.Println("No receipts found.")
 				return nil
 			}
+			indexReceipts(companyID, r.Receipts...)
 			if format == "json" {
 				for _, receipt := range r.Receipts {
 					output := formatter.ExtractReceiptFields(&receipt, fields)
@@ -160,11 +156,28 @@ var cmdReceiptsList = &cli.Command{
 	},
 }
 
+var (
+	flagReceiptShowLinks = &cli.BoolFlag{
+		Name:  "links",
+		Usage: "証憑ファイルが添付されている取引・振替伝票・経費申請を表示します",
+	}
+	flagReceiptShowRefresh = &cli.BoolFlag{
+		Name:  "refresh",
+		Usage: "--links の検索でキャッシュを使用せず、freee から取得し直します",
+	}
+)
+
 var cmdReceiptShow = &cli.Command{
 	Category:  "receipts",
 	Name:      "show",
 	Usage:     "指定したIDの証憑ファイルの情報を表示します",
 	ArgsUsage: "[ids...]",
+	Description: `指定したIDの証憑ファイルの情報を表示します。
+
+--links を指定すると、証憑ファイルが添付されている取引・振替伝票・経費申請も表示します。
+証憑の発行日（未設定の場合は登録日）の1か月前から3か月後までに発生した書類を検索します。
+検索結果は月ごとにキャッシュディレクトリ（$XDG_CACHE_HOME/ffbox）に1時間保存されます。
+ffbox で添付・解除や取引・経費申請・支払依頼の作成を行うと、キャッシュは削除されます。`,
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:  "format",
@@ -175,6 +188,8 @@ var cmdReceiptShow = &cli.Command{
 			Name:  "web",
 			Usage: "open the receipt in a web browser",
 		},
+		flagReceiptShowLinks,
+		flagReceiptShowRefresh,
 	},
 	Before: loadAppConfig,
 	Action: func(ctx context.Context, cmd *cli.Command) error {
@@ -203,16 +218,20 @@ var cmdReceiptShow = &cli.Command{
 			return nil
 		}
 
-		companyID, err := detectCompanyID(ctx, cmd)
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
 		if err != nil {
 			return err
 		}
-		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
 		if err != nil {
 			return err
 		}
 
 		format := cmd.String("format")
+		var finder *linkFinder
+		if cmd.Bool(flagReceiptShowLinks.Name) {
+			finder = newLinkFinder(freeeapiClient, companyID, cmd.Bool(flagReceiptShowRefresh.Name))
+		}
 		for i, id := range ids { // NOTE: とりあえず直列で取得している
 			params := &freeeapigen.GetReceiptParams{CompanyId: companyID}
 			resp, err := freeeapiClient.GetReceiptWithResponse(ctx, id, params)
@@ -222,8 +241,24 @@ var cmdReceiptShow = &cli.Command{
 			switch resp.StatusCode() {
 			case http.StatusOK:
 				r := resp.JSON200
+				indexReceipts(companyID, r.Receipt)
+				var links []receiptLink
+				var start, end string
+				if finder != nil {
+					links, start, end, err = finder.find(ctx, &r.Receipt)
+					if err != nil {
+						return fmt.Errorf("find links of receipt ID %d: %w", id, err)
+					}
+				}
 				if format == "json" {
-					b, err := json.Marshal(r.Receipt)
+					var v any = r.Receipt
+					if finder != nil {
+						v = struct {
+							freeeapigen.Receipt
+							Links []receiptLink `json:"links"`
+						}{r.Receipt, append([]receiptLink{}, links...)}
+					}
+					b, err := json.Marshal(v)
 					if err != nil {
 						return fmt.Errorf("marshal receipt ID %d: %w", id, err)
 					}
@@ -234,6 +269,12 @@ var cmdReceiptShow = &cli.Command{
 					if err := f.Format(&r.Receipt); err != nil {
 						return fmt.Errorf("format receipt ID %d: %w", id, err)
 					}
+					if finder != nil {
+						fmt.Println()
+						if err := printReceiptLinks(os.Stdout, id, links, start, end); err != nil {
+							return err
+						}
+					}
 					// Add separator between multiple receipts
 					if i < len(ids)-1 {
 						fmt.Println()
@@ -259,17 +300,19 @@ var cmdReceiptUpload = &cli.Command{
 		flagReceiptUploadDescription,
 		flagReceiptUploadDocumentType,
 		flagReceiptUploadQualifiedInvoice,
+		flagReceiptUploadInvoiceRegistrationNumber,
 		flagReceiptUploadReceiptMetadatumAmount,
 		flagReceiptUploadReceiptMetadatumIssueDate,
 		flagReceiptUploadReceiptMetadatumPartnerName,
+		flagReceiptUploadNoRules,
 	},
 	Before: loadAppConfig,
 	Action: func(ctx context.Context, cmd *cli.Command) error {
-		companyID, err := detectCompanyID(ctx, cmd)
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
 		if err != nil {
 			return err
 		}
-		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
 		if err != nil {
 			return err
 		}
@@ -298,10 +341,12 @@ var cmdReceiptUpload = &cli.Command{
 			}
 			r := strings.NewReader(string(content))
 			created, err := uploadReceipt(ctx, cmd, freeeapiClient, companyID, filename, r)
+			if created != nil {
+				fmt.Fprintf(cmd.Writer, "%d\n", created.Id)
+			}
 			if err != nil {
 				return fmt.Errorf("create receipt with stdin: %w", err)
 			}
-			fmt.Fprintf(cmd.Writer, "%d\n", created.Id)
 			return nil
 		}
 
@@ -316,12 +361,13 @@ var cmdReceiptUpload = &cli.Command{
 				filename = path.Base(filePath)
 			}
 			created, err := uploadReceipt(ctx, cmd, freeeapiClient, companyID, filename, f)
+			f.Close()
+			if created != nil {
+				fmt.Fprintf(cmd.Writer, "%d\n", created.Id) // Render created receipt ID
+			}
 			if err != nil {
-				f.Close()
 				return fmt.Errorf("create receipt with file %s: %w", filePath, err)
 			}
-			f.Close()
-			fmt.Fprintf(cmd.Writer, "%d\n", created.Id) // Render created receipt ID
 		}
 		return nil
 	},
@@ -332,6 +378,7 @@ var cmdReceiptUpload = &cli.Command{
 // - Description                  メモ (255文字以内)
 // - DocumentType                 書類の種類（receipt、invoice、other）
 // - QualifiedInvoice             適格請求書等（qualified、not_qualified、unselected）
+// - InvoiceRegistrationNumber    適格請求書発行事業者登録番号（作成後に証憑の更新で設定）
 // - ReceiptMetadatumAmount       金額
 // - ReceiptMetadatumIssueDate    発行日 (yyyy-mm-dd)
 // - ReceiptMetadatumPartnerName  発行元
@@ -345,28 +392,23 @@ var (
 		Usage: "証憑のメモ (255文字以内)",
 	}
 	flagReceiptUploadDocumentType = &cli.StringFlag{
-		Name:  "document-type",
-		Usage: "書類の種類（receipt、invoice、other）",
-		Validator: func(in string) error {
-			switch in {
-			case "", "receipt", "invoice", "other":
-				return nil
-			default:
-				return fmt.Errorf("書類の種類が不正です: %s", in)
-			}
-		},
+		Name:      "document-type",
+		Usage:     "書類の種類（receipt、invoice、other）",
+		Validator: validateDocumentType,
 	}
 	flagReceiptUploadQualifiedInvoice = &cli.StringFlag{
-		Name:  "qualified-invoice",
-		Usage: "適格請求書等（qualified、not_qualified、unselected）",
-		Validator: func(in string) error {
-			switch in {
-			case "", "qualified", "not_qualified", "unselected":
-				return nil
-			default:
-				return fmt.Errorf("適格請求書等の値が不正です: %s", in)
-			}
-		},
+		Name:      "qualified-invoice",
+		Usage:     "適格請求書等（qualified、not_qualified、unselected）",
+		Validator: validateQualifiedInvoice,
+	}
+	flagReceiptUploadInvoiceRegistrationNumber = &cli.StringFlag{
+		Name:      "invoice-registration-number",
+		Usage:     "適格請求書発行事業者登録番号（T と13桁の数字）。登録後に証憑を更新して設定します",
+		Validator: validateInvoiceRegistrationNumber,
+	}
+	flagReceiptUploadNoRules = &cli.BoolFlag{
+		Name:  "no-rules",
+		Usage: "分類ルールファイル（classify.rules_file）を適用しません",
 	}
 	flagReceiptUploadReceiptMetadatumAmount = &cli.UintFlag{
 		Name:  "amount",
@@ -394,6 +436,9 @@ var (
 
 func parseReceiptUploadFlags(cmd *cli.Command, params *freeeapigen.ReceiptCreateParams) error {
 	if v := cmd.String(flagReceiptUploadDescription.Name); v != "" {
+		if err := checkDescriptionLength(v); err != nil {
+			return err
+		}
 		params.Description = ptr(v)
 	}
 	if v := cmd.String(flagReceiptUploadDocumentType.Name); v != "" {
@@ -420,7 +465,7 @@ func parseReceiptUploadFlags(cmd *cli.Command, params *freeeapigen.ReceiptCreate
 			return fmt.Errorf("invalid qualified-invoice: %s", v)
 		}
 	}
-	if v := cmd.Int(flagReceiptUploadReceiptMetadatumAmount.Name); v != 0 {
+	if v := cmd.Uint(flagReceiptUploadReceiptMetadatumAmount.Name); v != 0 {
 		params.ReceiptMetadatumAmount = ptr(int64(v))
 	}
 	if v := cmd.String(flagReceiptUploadReceiptMetadatumIssueDate.Name); v != "" {
@@ -432,8 +477,20 @@ func parseReceiptUploadFlags(cmd *cli.Command, params *freeeapigen.ReceiptCreate
 	return nil
 }
 
+// checkDescriptionLength は、--description に指定した証憑のメモが上限の文字数以内か確認します。
+func checkDescriptionLength(v string) error {
+	if n := utf8.RuneCountInString(v); n > rules.MaxDescriptionLength {
+		return fmt.Errorf("--description は %d 文字以内で指定してください（%d 文字）", rules.MaxDescriptionLength, n)
+	}
+	return nil
+}
+
 // uploadReceipt is a helper function to create a receipt with given file path
 // and return the created Receipt object.
+//
+// If a step after the receipt is created fails, such as recording the audit
+// log or setting the invoice registration number, it returns the created
+// receipt together with the error, so that callers do not upload it again.
 func uploadReceipt(
 	ctx context.Context,
 	cmd *cli.Command,
@@ -449,6 +506,19 @@ func uploadReceipt(
 	if err := parseReceiptUploadFlags(cmd, params); err != nil {
 		return nil, err
 	}
+	content, err := params.Receipt.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("read receipt content: %w", err)
+	}
+	if !cmd.Bool(flagReceiptUploadNoRules.Name) {
+		rs, err := loadRules(ctx, "", false)
+		if err != nil {
+			return nil, err
+		}
+		if rs != nil {
+			applyUploadRules(rs, params, content)
+		}
+	}
 
 	body, contentType, err := freeeapi.EncodeReceiptCreateParams(params)
 	if err != nil {
@@ -460,10 +530,52 @@ func uploadReceipt(
 		return nil, fmt.Errorf("create receipt: %w", err)
 	}
 
-	if resp.StatusCode() == http.StatusCreated {
-		return ptr(resp.JSON201.Receipt), nil
+	if resp.StatusCode() != http.StatusCreated || resp.JSON201 == nil {
+		return nil, fmt.Errorf("got unexpected response: %s", resp.Status())
+	}
+	created := &resp.JSON201.Receipt
+
+	auditParams, err := receiptCreateAuditParams(params)
+	if err != nil {
+		return created, fmt.Errorf("証憑 %d: %w", created.Id, err)
+	}
+	entry := audit.Entry{
+		CompanyID:  companyID,
+		ReceiptIDs: []int64{created.Id},
+		Operation:  auditOpUpload,
+		FileHash:   audit.HashFile(content),
+	}
+	if err := recordAudit(ctx, entry, auditParams); err != nil {
+		return created, fmt.Errorf("証憑 %d: %w", created.Id, err)
+	}
+
+	// 登録番号は証憑の作成時に指定できないため、作成後に更新して設定する。
+	if v := cmd.String(flagReceiptUploadInvoiceRegistrationNumber.Name); v != "" {
+		updated, err := updateReceipt(ctx, apiClient, companyID, created.Id, freeeapigen.ReceiptUpdateParams{
+			CompanyId:                 companyID,
+			InvoiceRegistrationNumber: &v,
+		})
+		if err != nil {
+			return created, fmt.Errorf("証憑 %d は登録されましたが、登録番号を設定できませんでした: %w", created.Id, err)
+		}
+		created = updated
+	}
+	return created, nil
+}
+
+// receiptCreateAuditParams は、監査ログに記録する証憑のアップロードのパラメータを返します。
+// ファイルの内容はハッシュとして別に記録するため、ファイル名に置き換えます。
+func receiptCreateAuditParams(params *freeeapigen.ReceiptCreateParams) (map[string]any, error) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("marshal receipt params: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unmarshal receipt params: %w", err)
 	}
-	return nil, fmt.Errorf("got unexpected response: %s", resp.Status())
+	m["receipt"] = params.Receipt.Filename()
+	return m, nil
 }
 
 var ErrUnSupportedFileType = fmt.Errorf("unsupported file type")
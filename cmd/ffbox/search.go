@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/cache"
+	"github.com/micheam/freee-filebox-ctl/internal/config"
+	"github.com/micheam/freee-filebox-ctl/internal/formatter"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+	"github.com/micheam/freee-filebox-ctl/internal/index"
+)
+
+var (
+	flagSearchPartner = &cli.StringFlag{
+		Name:  "partner",
+		Usage: "発行元の名前に含まれる文字列",
+	}
+	flagSearchMinAmount = &cli.Int64Flag{
+		Name:  "min-amount",
+		Usage: "金額の下限",
+	}
+	flagSearchMaxAmount = &cli.Int64Flag{
+		Name:  "max-amount",
+		Usage: "金額の上限",
+	}
+	flagSearchStartIssueDate = &cli.StringFlag{
+		Name:      "start-issue-date",
+		Usage:     "発行日の開始日 (yyyy-mm-dd)",
+		Validator: validateDate,
+	}
+	flagSearchEndIssueDate = &cli.StringFlag{
+		Name:      "end-issue-date",
+		Usage:     "発行日の終了日 (yyyy-mm-dd)",
+		Validator: validateDate,
+	}
+	flagSearchStatus = &cli.StringFlag{
+		Name:  "status",
+		Usage: "ステータス (confirmed, ignored, deleted)。省略時は削除済みの証憑を除きます",
+		Validator: func(in string) error {
+			switch freeeapigen.ReceiptStatus(in) {
+			case "", freeeapigen.ReceiptStatusConfirmed, freeeapigen.ReceiptStatusIgnored, freeeapigen.ReceiptStatusDeleted:
+				return nil
+			default:
+				return fmt.Errorf("ステータスが不正です: %s", in)
+			}
+		},
+	}
+	flagSearchText = &cli.StringFlag{
+		Name:  "text",
+		Usage: "メモに含まれる文字列",
+	}
+	flagSearchRefresh = &cli.BoolFlag{
+		Name:  "refresh",
+		Usage: "検索の前に、前回の更新以降に登録された証憑を freee から取得してインデックスを更新します",
+	}
+	flagSearchSince = &cli.StringFlag{
+		Name:      "since",
+		Usage:     "--refresh で取得する証憑の登録日の開始日 (yyyy-mm-dd)。初回の省略時は2年前",
+		Validator: validateDate,
+	}
+	flagSearchFormat = &cli.StringFlag{
+		Name:  "format",
+		Usage: "出力フォーマット (table, json)",
+		Value: "table",
+	}
+	flagSearchFields = &cli.StringFlag{
+		Name:  "fields",
+		Usage: "表示するフィールドのカンマ区切りリスト (例: id,status,amount)",
+	}
+)
+
+var cmdSearch = &cli.Command{
+	Category: "receipts",
+	Name:     "search",
+	Usage:    "ローカルのインデックスから証憑ファイルを検索します",
+	Description: `キャッシュディレクトリ（$XDG_CACHE_HOME/ffbox）のインデックスから、freee に問い合わせずに証憑を検索します。
+
+インデックスには list、show、sync で取得した証憑が記録されます。
+--refresh を指定すると、前回の更新以降に登録された証憑を取得してから検索します。
+初回の --refresh では、省略時は2年前から登録された証憑を取得します。
+
+  ffbox search --refresh --since 2024-01-01
+  ffbox search --partner 文具 --min-amount 1000 --start-issue-date 2025-04-01`,
+	Flags: []cli.Flag{
+		flagSearchPartner,
+		flagSearchMinAmount,
+		flagSearchMaxAmount,
+		flagSearchStartIssueDate,
+		flagSearchEndIssueDate,
+		flagSearchStatus,
+		flagSearchText,
+		flagSearchRefresh,
+		flagSearchSince,
+		flagSearchFormat,
+		flagSearchFields,
+	},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		format := cmd.String(flagSearchFormat.Name)
+		switch format {
+		case "table", "json":
+			// valid
+		default:
+			return fmt.Errorf("format は table か json を指定してください")
+		}
+		store := newCacheStore()
+		if store == nil {
+			return fmt.Errorf("キャッシュディレクトリが見つかりません: $XDG_CACHE_HOME または $HOME を設定してください")
+		}
+
+		// オフラインで検索できるよう、API クライアントは --refresh の場合と、
+		// 事業所を名前で指定していてその解決が必要な場合にだけ用意する。
+		refresh := cmd.Bool(flagSearchRefresh.Name)
+		needAPI := refresh
+		if cmd.IsSet(flagCompanyID.Name) {
+			if _, err := strconv.ParseInt(cmd.String(flagCompanyID.Name), 10, 64); err != nil {
+				needAPI = true
+			}
+		}
+		var freeeapiClient *freeeapi.Client
+		if needAPI {
+			var err error
+			if freeeapiClient, err = prepareFreeeAPIClient(ctx, cmd); err != nil {
+				return err
+			}
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+		ix, err := index.Load(store, companyID)
+		if err != nil {
+			return fmt.Errorf("load receipt index: %w", err)
+		}
+		if refresh {
+			if err := refreshReceiptIndex(ctx, freeeapiClient, companyID, ix, cmd.String(flagSearchSince.Name)); err != nil {
+				return err
+			}
+			if err := ix.Save(store, companyID); err != nil {
+				return fmt.Errorf("save receipt index: %w", err)
+			}
+		} else if len(ix.Receipts) == 0 {
+			fmt.Fprintln(os.Stderr, "インデックスに証憑がありません: --refresh を指定して取得してください")
+		}
+
+		q := index.Query{
+			Partner:        cmd.String(flagSearchPartner.Name),
+			StartIssueDate: cmd.String(flagSearchStartIssueDate.Name),
+			EndIssueDate:   cmd.String(flagSearchEndIssueDate.Name),
+			Status:         freeeapigen.ReceiptStatus(cmd.String(flagSearchStatus.Name)),
+			Text:           cmd.String(flagSearchText.Name),
+		}
+		if cmd.IsSet(flagSearchMinAmount.Name) {
+			q.MinAmount = ptr(cmd.Int64(flagSearchMinAmount.Name))
+		}
+		if cmd.IsSet(flagSearchMaxAmount.Name) {
+			q.MaxAmount = ptr(cmd.Int64(flagSearchMaxAmount.Name))
+		}
+		receipts := ix.Search(q)
+
+		fields := splitFields(cmd.String(flagSearchFields.Name))
+		if format == "json" {
+			for _, receipt := range receipts {
+				b, err := json.Marshal(formatter.ExtractReceiptFields(&receipt, fields))
+				if err != nil {
+					return fmt.Errorf("marshal receipt: %w", err)
+				}
+				fmt.Println(string(b))
+			}
+			return nil
+		}
+		if len(receipts) == 0 {
+			fmt.Println("No receipts found.")
+			return nil
+		}
+		f := formatter.NewReceiptList(os.Stdout)
+		if err := f.FormatWithFields(receipts, fields); err != nil {
+			return fmt.Errorf("format receipts: %w", err)
+		}
+		return nil
+	},
+}
+
+// refreshReceiptIndex は、前回の更新日以降に登録された証憑を取得してインデックスに記録します。
+// since が前回までの範囲より前の場合は、since から取得します。
+// 取得した期間に登録された証憑のうち、一覧に含まれなかったものは削除済みとして記録します。
+func refreshReceiptIndex(ctx context.Context, client *freeeapi.Client, companyID int64, ix *index.Index, since string) error {
+	now := time.Now()
+	// 前回の更新日に登録された証憑は取得済みとは限らないため、その日から取得し直す。
+	start := ix.RefreshedUntil
+	if since != "" && (ix.Since == "" || since < ix.Since) {
+		ix.Since, start = since, since
+	}
+	if start == "" {
+		start = now.AddDate(-2, 0, 0).Format(time.DateOnly)
+		ix.Since = start
+	}
+	startDate, err := time.Parse(time.DateOnly, start)
+	if err != nil {
+		return fmt.Errorf("parse start date: %w", err)
+	}
+	receipts, err := listReceipts(ctx, client, companyID, startDate, now, nil)
+	if err != nil {
+		return err
+	}
+	seen := make(map[int64]bool, len(receipts))
+	for _, r := range receipts {
+		seen[r.Id] = true
+	}
+	for id, r := range ix.Receipts {
+		// created_at は yyyy-mm-dd で始まるため、文字列のまま開始日と比較できる。
+		if !seen[id] && r.CreatedAt >= start {
+			ix.MarkDeleted(id)
+		}
+	}
+	ix.Put(receipts...)
+	ix.RefreshedUntil = now.Format(time.DateOnly)
+	return nil
+}
+
+// newCacheStore は、ユーザーのキャッシュディレクトリの cache.Store を返します。
+// キャッシュディレクトリがない場合は nil を返します。
+func newCacheStore() *cache.Store {
+	dir := config.CacheDir()
+	if dir == "" {
+		return nil
+	}
+	return cache.New(dir)
+}
+
+// loadReceiptIndex は、事業所の証憑のインデックスを読み込みます。
+// 読み込めない場合は警告を表示して nil を返します。インデックスはあくまで補助のため、コマンドは続行します。
+func loadReceiptIndex(companyID int64) *index.Index {
+	store := newCacheStore()
+	if store == nil {
+		return nil
+	}
+	ix, err := index.Load(store, companyID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "証憑のインデックスを読み込めませんでした: %v\n", err)
+		return nil
+	}
+	return ix
+}
+
+// saveReceiptIndex は、事業所の証憑のインデックスを保存します。保存できない場合は警告を表示します。
+func saveReceiptIndex(companyID int64, ix *index.Index) {
+	store := newCacheStore()
+	if ix == nil || store == nil {
+		return
+	}
+	if err := ix.Save(store, companyID); err != nil {
+		fmt.Fprintf(os.Stderr, "証憑のインデックスを保存できませんでした: %v\n", err)
+	}
+}
+
+// indexReceipts は、取得した証憑をインデックスに記録します。
+func indexReceipts(companyID int64, receipts ...freeeapigen.Receipt) {
+	if ix := loadReceiptIndex(companyID); ix != nil {
+		ix.Put(receipts...)
+		saveReceiptIndex(companyID, ix)
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+	"github.com/micheam/freee-filebox-ctl/internal/index"
+)
+
+// syncStateFileName は、同期先ディレクトリに保存する同期状態ファイルの名前です。
+const syncStateFileName = ".ffbox-sync.json"
+
+var (
+	flagSyncSince = &cli.StringFlag{
+		Name:      "since",
+		Usage:     "同期の対象とする証憑の登録日の開始日 (yyyy-mm-dd)。初回の同期では必須",
+		Validator: validateDate,
+	}
+	flagSyncFull = &cli.BoolFlag{
+		Name:  "full",
+		Usage: "同期済みの期間全体を取得し直し、メタデータの変更と削除を検出します",
+	}
+)
+
+var cmdSync = &cli.Command{
+	Category:  "receipts",
+	Name:      "sync",
+	Usage:     "証憑ファイルとメタデータをディレクトリに同期します",
+	ArgsUsage: "<dir>",
+	Description: `ファイルボックスの証憑ファイルと、証憑ごとのメタデータ（JSON）をディレクトリに保存します。
+
+ファイルは登録月ごとのディレクトリに <証憑ID>.<拡張子> と <証憑ID>.json として保存されます。
+同期の状態はディレクトリ内の .ffbox-sync.json に記録され、2回目以降は前回の同期日以降に
+登録された証憑だけを取得します。中断した場合も、もう一度実行すると続きから同期します。
+
+取得した期間内でメタデータが変わった証憑は JSON を更新し、削除された証憑や無視された証憑は
+JSON の status に記録します（ファイルは残します）。--full を指定すると、同期済みの期間全体を
+取得し直して変更と削除を検出します。
+
+  ffbox sync --since 2024-01-01 ~/receipts
+  ffbox sync ~/receipts
+  ffbox sync --full ~/receipts`,
+	Flags: []cli.Flag{
+		flagSyncSince,
+		flagSyncFull,
+	},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("同期先のディレクトリを1つ指定してください")
+		}
+		dir := cmd.Args().First()
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+
+		state, err := loadSyncState(dir)
+		if err != nil {
+			return err
+		}
+		if state.CompanyID != 0 && state.CompanyID != companyID {
+			return fmt.Errorf("%s には事業所 %d の証憑が同期されています", dir, state.CompanyID)
+		}
+		state.CompanyID = companyID
+
+		// 前回の同期日に登録された証憑は取得済みとは限らないため、その日から取得し直す。
+		start := state.SyncedUntil
+		if cmd.Bool(flagSyncFull.Name) || start == "" {
+			start = state.Since
+		}
+		if since := cmd.String(flagSyncSince.Name); since != "" && (state.Since == "" || since < state.Since) {
+			state.Since, start = since, since
+		}
+		if start == "" {
+			return fmt.Errorf("初回の同期では --since で開始日を指定してください")
+		}
+
+		s := &receiptSyncer{client: freeeapiClient, companyID: companyID, dir: dir, state: state, index: loadReceiptIndex(companyID)}
+		// 中断した場合も、それまでに取得した証憑をインデックスに残す。
+		defer saveReceiptIndex(companyID, s.index)
+		today := time.Now().Format(time.DateOnly)
+		if err := s.sync(ctx, start, today); err != nil {
+			return err
+		}
+		state.SyncedUntil = today
+		if err := s.saveState(); err != nil {
+			return err
+		}
+		fmt.Printf("同期しました: 新規 %d 件、更新 %d 件、削除 %d 件、無視 %d 件\n", s.added, s.updated, s.deleted, s.ignored)
+		return nil
+	},
+}
+
+// syncState は、同期先ディレクトリの同期状態です。
+type syncState struct {
+	CompanyID int64 `json:"company_id"`
+	// Since は、同期の対象とする証憑の登録日の開始日です。
+	Since string `json:"since"`
+	// SyncedUntil は、最後に完了した同期の日付です。
+	SyncedUntil string `json:"synced_until,omitempty"`
+	// Receipts は、同期済みの証憑です。キーは証憑IDです。
+	Receipts map[int64]*syncedReceipt `json:"receipts"`
+}
+
+// syncedReceipt は、同期済みの証憑です。
+type syncedReceipt struct {
+	// File は、証憑ファイルの同期先ディレクトリからの相対パスです。
+	File string `json:"file"`
+	// Metadata は、メタデータ（JSON）の同期先ディレクトリからの相対パスです。
+	Metadata string `json:"metadata"`
+	// CreatedDate は、証憑の登録日 (yyyy-mm-dd) です。
+	CreatedDate string                    `json:"created_date"`
+	Status      freeeapigen.ReceiptStatus `json:"status"`
+	// Hash は、メタデータの SHA-256 です。変更の検出に使用します。
+	Hash string `json:"hash"`
+}
+
+// loadSyncState は、同期先ディレクトリの同期状態を読み込みます。
+// 同期状態ファイルがない場合は、空の同期状態を返します。
+func loadSyncState(dir string) (*syncState, error) {
+	state := &syncState{Receipts: map[int64]*syncedReceipt{}}
+	b, err := os.ReadFile(filepath.Join(dir, syncStateFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read sync state: %w", err)
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("parse sync state %s: %w", filepath.Join(dir, syncStateFileName), err)
+	}
+	if state.Receipts == nil {
+		state.Receipts = map[int64]*syncedReceipt{}
+	}
+	return state, nil
+}
+
+// receiptSyncer は、証憑ファイルとメタデータを同期先ディレクトリに保存します。
+type receiptSyncer struct {
+	client    *freeeapi.Client
+	companyID int64
+	dir       string
+	state     *syncState
+	// index は、同期した証憑を記録する証憑のインデックスです。nil の場合は記録しません。
+	index *index.Index
+
+	added, updated, deleted, ignored int
+}
+
+// sync は、登録日が start〜end の証憑を同期します。
+// 同期済みの証憑のうち、登録日が期間内で一覧に含まれないものは、個別に取得して状態を確認します。
+func (s *receiptSyncer) sync(ctx context.Context, start, end string) error {
+	startDate, err := time.Parse(time.DateOnly, start)
+	if err != nil {
+		return fmt.Errorf("parse start date: %w", err)
+	}
+	endDate, err := time.Parse(time.DateOnly, end)
+	if err != nil {
+		return fmt.Errorf("parse end date: %w", err)
+	}
+	receipts, err := listReceipts(ctx, s.client, s.companyID, startDate, endDate, nil)
+	if err != nil {
+		return err
+	}
+	seen := make(map[int64]bool, len(receipts))
+	for _, r := range receipts {
+		seen[r.Id] = true
+		if err := s.syncReceipt(ctx, &r); err != nil {
+			return err
+		}
+	}
+
+	var missing []int64
+	for id, entry := range s.state.Receipts {
+		if !seen[id] && entry.CreatedDate >= start && entry.CreatedDate <= end && entry.Status != freeeapigen.ReceiptStatusDeleted {
+			missing = append(missing, id)
+		}
+	}
+	slices.Sort(missing)
+	for _, id := range missing {
+		if err := s.checkMissing(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncReceipt は、証憑 r を同期します。
+// 未取得の証憑はファイルをダウンロードし、メタデータが変わった証憑はメタデータだけを更新します。
+// 証憑ごとに同期状態を保存するため、中断しても続きから同期できます。
+func (s *receiptSyncer) syncReceipt(ctx context.Context, r *freeeapigen.Receipt) error {
+	meta, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal receipt ID %d: %w", r.Id, err)
+	}
+	sum := sha256.Sum256(meta)
+	hash := hex.EncodeToString(sum[:])
+	if s.index != nil {
+		s.index.Put(*r)
+	}
+
+	entry, ok := s.state.Receipts[r.Id]
+	if ok && fileExists(filepath.Join(s.dir, entry.File)) {
+		if entry.Hash == hash {
+			return nil
+		}
+		if err := writeFileAtomic(filepath.Join(s.dir, entry.Metadata), meta); err != nil {
+			return err
+		}
+		if r.Status == freeeapigen.ReceiptStatusIgnored && entry.Status != r.Status {
+			s.ignored++
+			fmt.Printf("無視として記録しました: %s\n", entry.Metadata)
+		} else {
+			s.updated++
+			fmt.Printf("メタデータを更新しました: %s\n", entry.Metadata)
+		}
+		entry.Status, entry.Hash = r.Status, hash
+		return s.saveState()
+	}
+
+	created, err := time.Parse(time.RFC3339, r.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("parse created_at of receipt ID %d: %w", r.Id, err)
+	}
+	base := filepath.Join(created.Format("2006-01"), fmt.Sprint(r.Id))
+	entry = &syncedReceipt{
+		File:        base + receiptFileExt(r.MimeType),
+		Metadata:    base + ".json",
+		CreatedDate: created.Format(time.DateOnly),
+		Status:      r.Status,
+		Hash:        hash,
+	}
+	content, err := downloadReceipt(ctx, s.client, s.companyID, r.Id)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, entry.File), content); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, entry.Metadata), meta); err != nil {
+		return err
+	}
+	s.state.Receipts[r.Id] = entry
+	s.added++
+	fmt.Printf("ダウンロードしました: %s\n", entry.File)
+	return s.saveState()
+}
+
+// checkMissing は、一覧に含まれなかった同期済みの証憑を取得し、状態を記録します。
+// 取得できない証憑は削除されたものとして記録します。
+func (s *receiptSyncer) checkMissing(ctx context.Context, id int64) error {
+	resp, err := s.client.GetReceiptWithResponse(ctx, id, &freeeapigen.GetReceiptParams{CompanyId: s.companyID})
+	if err != nil {
+		return fmt.Errorf("get receipt ID %d: %w", id, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusOK && resp.JSON200 != nil:
+		return s.syncReceipt(ctx, &resp.JSON200.Receipt)
+	case resp.StatusCode() != http.StatusNotFound:
+		return fmt.Errorf("got unexpected response for receipt ID %d: %s", id, resp.Status())
+	}
+
+	entry := s.state.Receipts[id]
+	path := filepath.Join(s.dir, entry.Metadata)
+	var r freeeapigen.Receipt
+	if b, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(b, &r); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	} else {
+		r.Id = id
+	}
+	r.Status = freeeapigen.ReceiptStatusDeleted
+	if s.index != nil {
+		s.index.MarkDeleted(id)
+	}
+	meta, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal receipt ID %d: %w", id, err)
+	}
+	if err := writeFileAtomic(path, meta); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(meta)
+	entry.Status, entry.Hash = r.Status, hex.EncodeToString(sum[:])
+	s.deleted++
+	fmt.Printf("削除済みとして記録しました: %s\n", entry.Metadata)
+	return s.saveState()
+}
+
+// saveState は、同期状態を同期先ディレクトリに保存します。
+func (s *receiptSyncer) saveState() error {
+	b, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal sync state: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(s.dir, syncStateFileName), b)
+}
+
+// downloadReceipt は、証憑ファイルの内容をダウンロードします。
+func downloadReceipt(ctx context.Context, client *freeeapi.Client, companyID, receiptID int64) ([]byte, error) {
+	resp, err := client.DownloadReceiptWithResponse(ctx, receiptID, &freeeapigen.DownloadReceiptParams{CompanyId: companyID})
+	if err != nil {
+		return nil, fmt.Errorf("download receipt ID %d: %w", receiptID, err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("got unexpected response for receipt ID %d: %s", receiptID, resp.Status())
+	}
+	return resp.Body, nil
+}
+
+// receiptFileExts は、証憑ファイルの MIME タイプごとの拡張子です。
+var receiptFileExts = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/heic":      ".heic",
+	"image/webp":      ".webp",
+	"image/tiff":      ".tiff",
+}
+
+// receiptFileExt は、MIME タイプに対応する拡張子を返します。不明な場合は .bin を返します。
+func receiptFileExt(mimeType string) string {
+	if ext, ok := receiptFileExts[mimeType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// writeFileAtomic は、一時ファイルに書き込んでから置き換えることで、中断しても壊れたファイルが残らないように書き込みます。
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/audit"
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+	"github.com/micheam/freee-filebox-ctl/internal/invoice"
+)
+
+// ReceiptUpdateParams に設定可能なフラグ定義です。
+// 指定したフラグの項目だけを更新します。
+var (
+	flagReceiptUpdateDescription = &cli.StringFlag{
+		Name:  "description",
+		Usage: "証憑のメモ (255文字以内)",
+	}
+	flagReceiptUpdateDocumentType = &cli.StringFlag{
+		Name:      "document-type",
+		Usage:     "書類の種類（receipt、invoice、other）",
+		Validator: validateDocumentType,
+	}
+	flagReceiptUpdateQualifiedInvoice = &cli.StringFlag{
+		Name:      "qualified-invoice",
+		Usage:     "適格請求書等（qualified、not_qualified、unselected）",
+		Validator: validateQualifiedInvoice,
+	}
+	flagReceiptUpdateInvoiceRegistrationNumber = &cli.StringFlag{
+		Name:      "invoice-registration-number",
+		Usage:     "適格請求書発行事業者登録番号（T と13桁の数字）",
+		Validator: validateInvoiceRegistrationNumber,
+	}
+	flagReceiptUpdateAmount = &cli.Int64Flag{
+		Name:  "amount",
+		Usage: "証憑の金額",
+	}
+	flagReceiptUpdateIssueDate = &cli.StringFlag{
+		Name:      "issue-date",
+		Usage:     "証憑の発行日 (yyyy-mm-dd)",
+		Validator: validateDate,
+	}
+	flagReceiptUpdatePartnerName = &cli.StringFlag{
+		Name:  "partner-name",
+		Usage: "証憑の発行元",
+	}
+)
+
+var cmdReceiptUpdate = &cli.Command{
+	Category:  "receipts",
+	Name:      "update",
+	Usage:     "証憑ファイルの情報を更新します",
+	ArgsUsage: "<receipt-id>",
+	Description: `指定したフラグの項目だけを更新します。指定しなかった項目は変更されません。
+
+登録番号は、形式（T と13桁の数字）とチェックデジットを確認してから送信します。
+
+  ffbox update --qualified-invoice qualified --invoice-registration-number T7000012050002 123456789`,
+	Flags: []cli.Flag{
+		flagReceiptUpdateDescription,
+		flagReceiptUpdateDocumentType,
+		flagReceiptUpdateQualifiedInvoice,
+		flagReceiptUpdateInvoiceRegistrationNumber,
+		flagReceiptUpdateAmount,
+		flagReceiptUpdateIssueDate,
+		flagReceiptUpdatePartnerName,
+	},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("証憑IDを1つ指定してください")
+		}
+		receiptID, err := strconv.ParseInt(cmd.Args().First(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid receipt ID: %q", cmd.Args().First())
+		}
+		if err := checkDescriptionLength(cmd.String(flagReceiptUpdateDescription.Name)); err != nil {
+			return err
+		}
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+
+		params := freeeapigen.ReceiptUpdateParams{CompanyId: companyID}
+		changed := false
+		if cmd.IsSet(flagReceiptUpdateDescription.Name) {
+			params.Description = ptr(cmd.String(flagReceiptUpdateDescription.Name))
+			changed = true
+		}
+		if cmd.IsSet(flagReceiptUpdateDocumentType.Name) {
+			params.DocumentType = ptr(freeeapigen.ReceiptUpdateParamsDocumentType(cmd.String(flagReceiptUpdateDocumentType.Name)))
+			changed = true
+		}
+		if cmd.IsSet(flagReceiptUpdateQualifiedInvoice.Name) {
+			params.QualifiedInvoice = ptr(freeeapigen.ReceiptUpdateParamsQualifiedInvoice(cmd.String(flagReceiptUpdateQualifiedInvoice.Name)))
+			changed = true
+		}
+		if cmd.IsSet(flagReceiptUpdateInvoiceRegistrationNumber.Name) {
+			params.InvoiceRegistrationNumber = ptr(cmd.String(flagReceiptUpdateInvoiceRegistrationNumber.Name))
+			changed = true
+		}
+		if cmd.IsSet(flagReceiptUpdateAmount.Name) || cmd.IsSet(flagReceiptUpdateIssueDate.Name) || cmd.IsSet(flagReceiptUpdatePartnerName.Name) {
+			// receipt_metadatum はまとめて更新されるため、指定しなかった項目には現在の値を送る。
+			receipt, err := getReceipt(ctx, freeeapiClient, companyID, receiptID)
+			if err != nil {
+				return err
+			}
+			params.ReceiptMetadatum = receipt.ReceiptMetadatum
+			if params.ReceiptMetadatum == nil {
+				params.ReceiptMetadatum = &struct {
+					Amount      *int64  `json:"amount"`
+					IssueDate   *string `json:"issue_date"`
+					PartnerName *string `json:"partner_name"`
+				}{}
+			}
+			if cmd.IsSet(flagReceiptUpdateAmount.Name) {
+				params.ReceiptMetadatum.Amount = ptr(cmd.Int64(flagReceiptUpdateAmount.Name))
+			}
+			if cmd.IsSet(flagReceiptUpdateIssueDate.Name) {
+				params.ReceiptMetadatum.IssueDate = ptr(cmd.String(flagReceiptUpdateIssueDate.Name))
+			}
+			if cmd.IsSet(flagReceiptUpdatePartnerName.Name) {
+				params.ReceiptMetadatum.PartnerName = ptr(cmd.String(flagReceiptUpdatePartnerName.Name))
+			}
+			changed = true
+		}
+		if !changed {
+			return fmt.Errorf("更新する項目をフラグで指定してください")
+		}
+
+		if _, err := updateReceipt(ctx, freeeapiClient, companyID, receiptID, params); err != nil {
+			return err
+		}
+		fmt.Printf("証憑 %d を更新しました\n", receiptID)
+		return nil
+	},
+}
+
+// updateReceipt は、証憑を更新して監査ログに記録し、更新後の証憑を返します。
+func updateReceipt(ctx context.Context, client *freeeapi.Client, companyID, receiptID int64, params freeeapigen.ReceiptUpdateParams) (*freeeapigen.Receipt, error) {
+	resp, err := client.UpdateReceiptWithResponse(ctx, receiptID, params)
+	if err != nil {
+		return nil, fmt.Errorf("update receipt ID %d: %w", receiptID, err)
+	}
+	if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+		return nil, fmt.Errorf("got unexpected response for receipt ID %d: %s", receiptID, resp.Status())
+	}
+	updated := &resp.JSON200.Receipt
+	entry := audit.Entry{
+		CompanyID:  companyID,
+		ReceiptIDs: []int64{receiptID},
+		Operation:  auditOpUpdate,
+	}
+	if err := recordAudit(ctx, entry, params); err != nil {
+		return nil, fmt.Errorf("証憑 %d: %w", receiptID, err)
+	}
+	indexReceipts(companyID, *updated)
+	return updated, nil
+}
+
+// validateDocumentType は、書類の種類のフラグの値を検証します。
+func validateDocumentType(in string) error {
+	switch in {
+	case "", "receipt", "invoice", "other":
+		return nil
+	default:
+		return fmt.Errorf("書類の種類が不正です: %s", in)
+	}
+}
+
+// validateQualifiedInvoice は、適格請求書等のフラグの値を検証します。
+func validateQualifiedInvoice(in string) error {
+	switch in {
+	case "", "qualified", "not_qualified", "unselected":
+		return nil
+	default:
+		return fmt.Errorf("適格請求書等の値が不正です: %s", in)
+	}
+}
+
+// validateInvoiceRegistrationNumber は、登録番号のフラグの値の形式とチェックデジットを検証します。
+func validateInvoiceRegistrationNumber(in string) error {
+	if in == "" {
+		return nil
+	}
+	if err := invoice.ValidateRegistrationNumber(in); err != nil {
+		return errors.New(describeInvoiceRegistrationNumberError(in, err))
+	}
+	return nil
+}
+
+// describeInvoiceRegistrationNumberError は、登録番号 v の検証エラーの説明を返します。
+func describeInvoiceRegistrationNumberError(v string, err error) string {
+	if errors.Is(err, invoice.ErrCheckDigit) {
+		return fmt.Sprintf("登録番号 %s のチェックデジットが一致しません（入力誤りの可能性があります）", v)
+	}
+	return fmt.Sprintf("登録番号 %q は T と13桁の数字で指定してください", v)
+}
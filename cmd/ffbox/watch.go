@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/micheam/freee-filebox-ctl/internal/freeeapi"
+)
+
+const (
+	// watchUploadedDir は、アップロードしたファイルを移動するサブディレクトリの名前です。
+	watchUploadedDir = "uploaded"
+	// watchFailedDir は、アップロードに失敗したファイルを移動するサブディレクトリの名前です。
+	watchFailedDir = "failed"
+	// watchLogFileName は、監視するディレクトリに保存する処理結果のログファイルの名前です。
+	watchLogFileName = "ffbox-watch.log"
+)
+
+// watchExts は、アップロードの対象とするファイルの拡張子です。
+var watchExts = []string{".jpg", ".jpeg", ".png", ".pdf"}
+
+var (
+	flagWatchInterval = &cli.DurationFlag{
+		Name:  "interval",
+		Usage: "ディレクトリを確認する間隔",
+		Value: 5 * time.Second,
+	}
+	flagWatchOnce = &cli.BoolFlag{
+		Name:  "once",
+		Usage: "処理待ちのファイルがなくなったら終了します",
+	}
+)
+
+var cmdWatch = &cli.Command{
+	Category:  "receipts",
+	Name:      "watch",
+	Usage:     "ディレクトリを監視し、追加された証憑ファイルをアップロードします",
+	ArgsUsage: "<dir>",
+	Description: `ディレクトリを --interval ごとに確認し、追加された JPEG・PNG・PDF ファイルをアップロードします。
+スキャナーの保存先などを指定しておくと、保存されたファイルが自動的にファイルボックスに登録されます。
+
+書き込み中のファイルをアップロードしないよう、サイズと更新日時が前回の確認から変わっていない
+ファイルだけをアップロードします。アップロードしたファイルは uploaded/ に、失敗したファイルは
+failed/ に移動し、結果をディレクトリ内の ffbox-watch.log に記録します。
+failed/ のファイルは、原因を解消してからディレクトリに戻すと、もう一度アップロードされます。
+証憑の登録後に監査ログへの記録などに失敗した場合は、証憑は登録済みのため uploaded/ に移動し、
+ログに警告（warning=）を記録します。
+
+メモ・書類の種類・適格請求書等は ffbox upload と同じく、フラグと分類ルールファイルから設定します。
+Ctrl-C で終了します。--once を指定すると、処理待ちのファイルがなくなった時点で終了します。
+
+  ffbox watch ~/scans
+  ffbox watch --interval 30s --document-type receipt ~/scans`,
+	Flags: []cli.Flag{
+		flagWatchInterval,
+		flagWatchOnce,
+		flagReceiptUploadDescription,
+		flagReceiptUploadDocumentType,
+		flagReceiptUploadQualifiedInvoice,
+		flagReceiptUploadNoRules,
+	},
+	Before: loadAppConfig,
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Args().Len() != 1 {
+			return fmt.Errorf("監視するディレクトリを1つ指定してください")
+		}
+		dir := cmd.Args().First()
+		if info, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("監視するディレクトリが見つかりません: %w", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("%s はディレクトリではありません", dir)
+		}
+		interval := cmd.Duration(flagWatchInterval.Name)
+		if interval <= 0 {
+			return fmt.Errorf("--interval には正の値を指定してください")
+		}
+
+		freeeapiClient, err := prepareFreeeAPIClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		companyID, err := detectCompanyID(ctx, cmd, freeeapiClient)
+		if err != nil {
+			return err
+		}
+
+		w, err := newReceiptWatcher(dir)
+		if err != nil {
+			return err
+		}
+		defer w.close()
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		fmt.Fprintf(os.Stderr, "%s を監視しています（Ctrl-C で終了します）\n", dir)
+		once := cmd.Bool(flagWatchOnce.Name)
+		for {
+			ready, waiting, err := w.scan()
+			if err != nil {
+				return err
+			}
+			for _, name := range ready {
+				if err := w.upload(ctx, cmd, freeeapiClient, companyID, name); err != nil {
+					return err
+				}
+				if ctx.Err() != nil {
+					break
+				}
+			}
+			if once && waiting == 0 {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				fmt.Fprintln(os.Stderr, "監視を終了します")
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	},
+}
+
+// receiptWatcher は、ディレクトリに追加された証憑ファイルをアップロードします。
+type receiptWatcher struct {
+	dir string
+	log *os.File
+	// pending は、前回の確認で見つかった、アップロード前のファイルの状態です。
+	pending map[string]watchedFile
+}
+
+// watchedFile は、書き込みが終わったかどうかを判断するためのファイルの状態です。
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// newReceiptWatcher は、dir を監視する receiptWatcher を返します。
+// 移動先のサブディレクトリがなければ作成し、ログファイルを開きます。
+func newReceiptWatcher(dir string) (*receiptWatcher, error) {
+	for _, sub := range []string{watchUploadedDir, watchFailedDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("create %s directory: %w", sub, err)
+		}
+	}
+	log, err := os.OpenFile(filepath.Join(dir, watchLogFileName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open watch log: %w", err)
+	}
+	return &receiptWatcher{dir: dir, log: log, pending: map[string]watchedFile{}}, nil
+}
+
+func (w *receiptWatcher) close() error {
+	return w.log.Close()
+}
+
+// scan は、ディレクトリを確認し、前回の確認からサイズと更新日時が変わっていないファイルを
+// ready として返します。waiting は、書き込み中の可能性があり、次回以降に確認するファイルの数です。
+func (w *receiptWatcher) scan() (ready []string, waiting int, err error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read directory %s: %w", w.dir, err)
+	}
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") ||
+			!slices.Contains(watchExts, strings.ToLower(filepath.Ext(name))) {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue // 確認中に移動・削除された
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("stat %s: %w", name, err)
+		}
+		seen[name] = true
+		current := watchedFile{size: info.Size(), modTime: info.ModTime()}
+		if prev, ok := w.pending[name]; ok && prev == current && current.size > 0 {
+			ready = append(ready, name)
+			continue
+		}
+		w.pending[name] = current
+		waiting++
+	}
+	for name := range w.pending {
+		if !seen[name] {
+			delete(w.pending, name)
+		}
+	}
+	return ready, waiting, nil
+}
+
+// upload は、ファイルをアップロードし、結果に応じて uploaded/ か failed/ に移動します。
+// 中断された場合は、ファイルをそのまま残します。
+// ファイルを移動できなかった場合は、同じファイルを繰り返しアップロードしないようエラーを返します。
+func (w *receiptWatcher) upload(ctx context.Context, cmd *cli.Command, client *freeeapi.Client, companyID int64, name string) error {
+	delete(w.pending, name)
+	f, err := os.Open(filepath.Join(w.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // 確認後に移動・削除された
+	}
+	if err != nil {
+		return fmt.Errorf("open file %s: %w", name, err)
+	}
+	created, uploadErr := uploadReceipt(ctx, cmd, client, companyID, name, f)
+	f.Close()
+	if created == nil && uploadErr != nil && ctx.Err() != nil {
+		return nil
+	}
+
+	// 証憑の作成後の処理（監査ログへの記録など）に失敗した場合も、証憑は登録されているため
+	// uploaded/ に移動し、警告としてログに記録する。failed/ に移動すると、再試行で重複して登録される。
+	sub, detail := watchFailedDir, ""
+	if created != nil {
+		sub, detail = watchUploadedDir, fmt.Sprintf("receipt=%d", created.Id)
+		if uploadErr != nil {
+			detail += " warning=" + uploadErr.Error()
+		}
+	} else {
+		detail = "error=" + uploadErr.Error()
+	}
+	dest, err := w.move(name, sub)
+	if err != nil {
+		w.writeLog(name, sub, "", "error="+err.Error())
+		return fmt.Errorf("%s を %s/ に移動できませんでした: %w", name, sub, err)
+	}
+	w.writeLog(name, sub, dest, detail)
+
+	if created == nil {
+		fmt.Fprintf(os.Stderr, "アップロードに失敗しました: %s -> %s: %v\n", name, dest, uploadErr)
+		return nil
+	}
+	fmt.Printf("アップロードしました: %s -> %s（証憑 %d）\n", name, dest, created.Id)
+	if uploadErr != nil {
+		fmt.Fprintf(os.Stderr, "警告: %s: %v\n", name, uploadErr)
+	}
+	return nil
+}
+
+// move は、ファイルをサブディレクトリ sub に移動し、移動先のパス（dir からの相対パス）を返します。
+// 同じ名前のファイルがすでにある場合は、name_2 のように番号を付けます。
+func (w *receiptWatcher) move(name, sub string) (string, error) {
+	entries, err := os.ReadDir(filepath.Join(w.dir, sub))
+	if err != nil {
+		return "", err
+	}
+	used := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		used[entry.Name()] = true
+	}
+	ext := filepath.Ext(name)
+	dest := filepath.Join(sub, uniqueFileName(used, strings.TrimSuffix(name, ext), ext))
+	if err := os.Rename(filepath.Join(w.dir, name), filepath.Join(w.dir, dest)); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// writeLog は、ファイルの処理結果をタブ区切りの1行としてログファイルに追記します。
+// ログに書き込めなくても処理は続けるため、エラーは標準エラー出力に表示するだけです。
+func (w *receiptWatcher) writeLog(name, result, dest, detail string) {
+	detail = strings.NewReplacer("\t", " ", "\n", " ").Replace(detail)
+	line := strings.Join([]string{time.Now().Format(time.RFC3339), result, name, dest, detail}, "\t")
+	if _, err := fmt.Fprintln(w.log, line); err != nil {
+		fmt.Fprintf(os.Stderr, "ログに書き込めませんでした: %v\n", err)
+	}
+}
@@ -0,0 +1,195 @@
+// Package audit records the mutating operations done through ffbox in a
+// local, append-only JSON Lines log.
+//
+// Each entry holds the hash of the previous entry, and its own hash covers
+// all of its fields. Modifying, removing or reordering an entry breaks the
+// chain, which Verify detects. Removing entries at the end of the log cannot
+// be detected from the log alone, so the hash of the last entry should be
+// kept elsewhere when it matters.
+//
+// Append holds an exclusive lock on a sidecar file (the log path with
+// ".lock" appended) while it reads the last entry and writes the new one, so
+// that processes appending at the same time do not fork the chain.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry is an entry of the audit log.
+type Entry struct {
+	// Seq is the sequence number of the entry, starting from 1.
+	Seq int64 `json:"seq"`
+	// Time is the time the operation was done.
+	Time time.Time `json:"time"`
+	// User is the name of the OS user who did the operation.
+	User string `json:"user"`
+	// CompanyID is the ID of the company the operation was done in.
+	CompanyID int64 `json:"company_id"`
+	// ReceiptIDs are the IDs of the receipts the operation affected.
+	ReceiptIDs []int64 `json:"receipt_ids,omitempty"`
+	// Operation is the name of the operation, such as "upload" or "attach".
+	Operation string `json:"operation"`
+	// Target is the resource the operation was done to other than receipts,
+	// such as "deal:123".
+	Target string `json:"target,omitempty"`
+	// FileHash is the SHA-256 hash of the uploaded file, in hex.
+	FileHash string `json:"file_hash,omitempty"`
+	// Params are the parameters sent to the freee API.
+	Params json.RawMessage `json:"params,omitempty"`
+	// PrevHash is the hash of the previous entry, or empty for the first one.
+	PrevHash string `json:"prev_hash"`
+	// Hash is the hash of this entry.
+	Hash string `json:"hash"`
+}
+
+// computeHash returns the SHA-256 hash of e in hex, computed over its JSON
+// encoding with the Hash field cleared.
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HashFile returns the SHA-256 hash of the file content in hex.
+func HashFile(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Append appends e to the log at path, creating it if needed. It sets the
+// sequence number and the hashes of e, and returns the appended entry.
+// It is safe to call from multiple processes at the same time.
+func Append(path string, e Entry) (Entry, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return Entry{}, fmt.Errorf("create audit log directory: %w", err)
+	}
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return Entry{}, fmt.Errorf("lock audit log: %w", err)
+	}
+	defer unlock()
+	last, err := lastEntry(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.Seq, e.PrevHash = 1, ""
+	if last != nil {
+		e.Seq, e.PrevHash = last.Seq+1, last.Hash
+	}
+	// Normalize the time so that the hash survives the JSON round trip.
+	e.Time = e.Time.UTC()
+	if e.Hash, err = e.computeHash(); err != nil {
+		return Entry{}, fmt.Errorf("hash audit entry: %w", err)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return Entry{}, fmt.Errorf("marshal audit entry: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return Entry{}, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return Entry{}, fmt.Errorf("write audit log: %w", err)
+	}
+	return e, f.Close()
+}
+
+// lastEntry returns the last entry of the log, or nil if the log is empty or
+// does not exist.
+func lastEntry(path string) (*Entry, error) {
+	var last *Entry
+	err := scan(path, func(_ int, e *Entry) error {
+		last = e
+		return nil
+	})
+	return last, err
+}
+
+// Read returns all entries of the log. It returns no entries if the log does
+// not exist.
+func Read(path string) ([]Entry, error) {
+	var entries []Entry
+	err := scan(path, func(_ int, e *Entry) error {
+		entries = append(entries, *e)
+		return nil
+	})
+	return entries, err
+}
+
+// Verify checks the hash chain of the log and returns the number of entries
+// and the last entry. It returns an error describing the first broken entry.
+func Verify(path string) (n int, last *Entry, err error) {
+	err = scan(path, func(line int, e *Entry) error {
+		want := int64(1)
+		prevHash := ""
+		if last != nil {
+			want, prevHash = last.Seq+1, last.Hash
+		}
+		if e.Seq != want {
+			return fmt.Errorf("line %d: seq is %d, want %d", line, e.Seq, want)
+		}
+		if e.PrevHash != prevHash {
+			return fmt.Errorf("line %d (seq %d): prev_hash does not match the previous entry", line, e.Seq)
+		}
+		hash, err := e.computeHash()
+		if err != nil {
+			return fmt.Errorf("line %d (seq %d): %w", line, e.Seq, err)
+		}
+		if e.Hash != hash {
+			return fmt.Errorf("line %d (seq %d): hash does not match the content", line, e.Seq)
+		}
+		n, last = n+1, e
+		return nil
+	})
+	return n, last, err
+}
+
+// scan calls fn for each entry of the log with its line number.
+func scan(path string, fn func(line int, e *Entry) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(b)) > 0 {
+			e := &Entry{}
+			if err := json.Unmarshal(b, e); err != nil {
+				return fmt.Errorf("line %d: parse audit entry: %w", line, err)
+			}
+			if err := fn(line, e); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read audit log: %w", err)
+		}
+	}
+}
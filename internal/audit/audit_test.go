@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	for i, op := range []string{"upload", "attach", "detach"} {
+		e, err := Append(path, Entry{
+			Time:       time.Date(2025, 4, 1, 10, i, 0, 0, time.Local),
+			User:       "tester",
+			CompanyID:  1,
+			ReceiptIDs: []int64{int64(i + 1)},
+			Operation:  op,
+			Params:     json.RawMessage(`{"description": "<memo>"}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Seq != int64(i+1) {
+			t.Errorf("Append() seq = %d, want %d", e.Seq, i+1)
+		}
+	}
+	n, last, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if n != 3 || last.Operation != "detach" {
+		t.Errorf("Verify() = %d, %+v", n, last)
+	}
+
+	// Tampering with an entry breaks the chain.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(b), `"operation":"attach"`, `"operation":"detach"`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Verify(path); err == nil || !strings.Contains(err.Error(), "seq 2") {
+		t.Errorf("Verify() error = %v, want an error at seq 2", err)
+	}
+
+	// Removing an entry breaks the chain.
+	lines := strings.SplitAfter(string(b), "\n")
+	if err := os.WriteFile(path, []byte(lines[0]+lines[2]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Verify(path); err == nil {
+		t.Error("Verify() succeeded after removing an entry")
+	}
+}
+
+// TestAppendConcurrently runs this test binary in several processes that
+// append to the same log at the same time, as ffbox watch and another ffbox
+// command do, and checks that the chain is not forked.
+func TestAppendConcurrently(t *testing.T) {
+	if path := os.Getenv("AUDIT_TEST_APPEND_PATH"); path != "" {
+		for i := range 50 {
+			if _, err := Append(path, Entry{Time: time.Now(), CompanyID: 1, ReceiptIDs: []int64{int64(i)}, Operation: "upload"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	const procs = 4
+	cmds := make([]*exec.Cmd, procs)
+	for i := range cmds {
+		cmds[i] = exec.Command(os.Args[0], "-test.run=^TestAppendConcurrently$")
+		cmds[i].Env = append(os.Environ(), "AUDIT_TEST_APPEND_PATH="+path)
+		if err := cmds[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, last, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if n != procs*50 || last.Seq != procs*50 {
+		t.Errorf("Verify() = %d entries, last seq %d, want %d", n, last.Seq, procs*50)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !unix
+
+package audit
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// lockStaleAfter is the age after which a lock file is considered left
+// behind by a process that exited without releasing it.
+const lockStaleAfter = 30 * time.Second
+
+// lockFile takes an exclusive lock by creating the file at path, and returns
+// a function that releases the lock by removing it. It blocks while another
+// process holds the lock.
+func lockFile(path string) (unlock func() error, err error) {
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			f.Close()
+			return func() error { return os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
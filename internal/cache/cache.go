@@ -0,0 +1,121 @@
+// Package cache stores JSON values in files under a directory, so that
+// results of expensive API scans can be reused across invocations.
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Store is a file based cache. Each key is stored as a JSON file under Dir.
+type Store struct {
+	Dir string
+}
+
+// New returns a Store that keeps its files under dir.
+func New(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// path returns the file path for the key. Keys are slash separated paths
+// relative to Dir, such as "links/123/2025-04".
+func (s *Store) path(key string) (string, error) {
+	if key == "" || !filepath.IsLocal(filepath.FromSlash(key)) || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid cache key: %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)+".json"), nil
+}
+
+// Get reads the value stored for the key into v.
+//
+// It returns false if the key is not stored, or if it was stored more than
+// maxAge ago. A non-positive maxAge means the value never expires.
+func (s *Store) Get(key string, maxAge time.Duration, v any) (bool, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat cache file: %w", err)
+	}
+	if maxAge > 0 && time.Since(info.ModTime()) > maxAge {
+		return false, nil
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return false, fmt.Errorf("read cache file: %w", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		// A broken file is treated as a miss; it is overwritten by the next Put.
+		return false, nil
+	}
+	return true, nil
+}
+
+// Put stores v for the key, replacing the previous value.
+// The file is replaced atomically, so a concurrent Get never sees a partial value.
+func (s *Store) Put(key string, v any) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal cache value: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create cache file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("write cache file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write cache file: %w", err)
+	}
+	if err := os.Rename(f.Name(), p); err != nil {
+		return fmt.Errorf("write cache file: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the value stored for the key. It is not an error if the key is not stored.
+func (s *Store) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove cache file: %w", err)
+	}
+	return nil
+}
+
+// DeletePrefix removes the values stored for all keys under the slash
+// separated prefix, such as "links/123" for "links/123/2025-04".
+// It is not an error if no key is stored under the prefix.
+func (s *Store) DeletePrefix(prefix string) error {
+	p, err := s.path(prefix)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(strings.TrimSuffix(p, ".json")); err != nil {
+		return fmt.Errorf("remove cache files: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	s := New(t.TempDir())
+
+	var got []int64
+	if ok, err := s.Get("links/1/2025-04", 0, &got); err != nil || ok {
+		t.Fatalf("Get() before Put = %v, %v, want false, nil", ok, err)
+	}
+	if err := s.Put("links/1/2025-04", []int64{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.Get("links/1/2025-04", time.Hour, &got); err != nil || !ok {
+		t.Fatalf("Get() after Put = %v, %v, want true, nil", ok, err)
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Get() = %v, want [1 2]", got)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(s.Dir, "links", "1", "2025-04.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Get("links/1/2025-04", time.Hour, &got); ok {
+		t.Error("Get() returned an expired value")
+	}
+	if ok, _ := s.Get("links/1/2025-04", 0, &got); !ok {
+		t.Error("Get() with no max age did not return the value")
+	}
+
+	if err := s.Delete("links/1/2025-04"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Get("links/1/2025-04", 0, &got); ok {
+		t.Error("Get() returned a deleted value")
+	}
+
+	for _, key := range []string{"links/1/2025-04", "links/1/2025-05", "links/2/2025-04"} {
+		if err := s.Put(key, []int64{1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.DeletePrefix("links/1"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"links/1/2025-04": false, "links/1/2025-05": false, "links/2/2025-04": true} {
+		if ok, _ := s.Get(key, 0, &got); ok != want {
+			t.Errorf("Get(%q) after DeletePrefix = %v, want %v", key, ok, want)
+		}
+	}
+	if err := s.DeletePrefix("links/1"); err != nil {
+		t.Errorf("DeletePrefix() of a missing prefix: %v", err)
+	}
+
+	for _, key := range []string{"", "../x", "/abs"} {
+		if err := s.Put(key, 1); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", key)
+		}
+	}
+}
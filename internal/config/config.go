@@ -1,12 +1,15 @@
 // Package config provides functionality to load application configuration
-// from a TOML file following the XDG Base Directory specification.
+// from a TOML file following the XDG Base Directory specification,
+// optionally layered with a project-local .ffbox.toml.
 package config
 
 import (
 	_ "embed"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/pelletier/go-toml/v2"
 )
@@ -14,6 +17,9 @@ import (
 const (
 	appName        = "ffbox"
 	configFileName = "config.toml"
+
+	// projectConfigFileName is the name of the project-local config file
+	projectConfigFileName = ".ffbox.toml"
 )
 
 //go:embed config.example.toml
@@ -26,12 +32,128 @@ type Config struct {
 		TokenFile string `toml:"token_file"`
 		// LocalAddr is the local address for OAuth2 callback server
 		LocalAddr string `toml:"local_addr"`
+		// AuthURL is the authorization endpoint of the OAuth2 provider
+		AuthURL string `toml:"auth_url"`
+		// TokenURL is the token endpoint of the OAuth2 provider
+		TokenURL string `toml:"token_url"`
 	} `toml:"oauth2"`
 
 	Freee struct {
 		// CompanyID is the default freee company ID to use for operations
 		CompanyID int64 `toml:"company_id"`
+		// APIEndpoint is the base URL of the freee API
+		APIEndpoint string `toml:"api_endpoint"`
 	} `toml:"freee"`
+
+	Deal struct {
+		// AccountItemID is the default account item ID of deals created from receipts
+		AccountItemID int64 `toml:"account_item_id"`
+		// TaxCode is the default tax code of deals created from receipts
+		TaxCode int64 `toml:"tax_code"`
+	} `toml:"deal"`
+
+	Classify struct {
+		// RulesFile is the path to the TOML file of receipt classification rules
+		RulesFile string `toml:"rules_file"`
+	} `toml:"classify"`
+
+	Lint struct {
+		// QualifiedInvoiceThreshold is the amount from which receipts whose
+		// qualified invoice status is not selected are reported by lint.
+		QualifiedInvoiceThreshold int64 `toml:"qualified_invoice_threshold"`
+	} `toml:"lint"`
+
+	Audit struct {
+		// LogFile is the path to the audit log of mutating operations.
+		// If empty, audit.jsonl in StateDir is used.
+		LogFile string `toml:"log_file"`
+	} `toml:"audit"`
+
+	// Profiles holds named settings for each company or OAuth2 application.
+	// A profile is selected with --profile or FFBOX_PROFILE.
+	Profiles map[string]Profile `toml:"profiles,omitempty"`
+
+	// activeProfile is the name of the profile selected by UseProfile
+	activeProfile string
+	// origins records which file each value came from, as loaded by LoadWithOrigins
+	origins Origins
+}
+
+// Profile represents a named set of settings that overrides the top-level ones
+type Profile struct {
+	// CompanyID is the freee company ID to use with this profile
+	CompanyID int64 `toml:"company_id"`
+	// ClientIDEnv is the name of the environment variable holding the OAuth2 client ID
+	ClientIDEnv string `toml:"client_id_env"`
+	// ClientSecretEnv is the name of the environment variable holding the OAuth2 client secret
+	ClientSecretEnv string `toml:"client_secret_env"`
+	// TokenFile is the path to the file where OAuth2 tokens for this profile are stored.
+	// Defaults to "token-<profile name>.json".
+	TokenFile string `toml:"token_file"`
+}
+
+// TokenFileOrDefault returns TokenFile, or "token-<name>.json" if it is empty
+func (p Profile) TokenFileOrDefault(name string) string {
+	if p.TokenFile != "" {
+		return p.TokenFile
+	}
+	return fmt.Sprintf("token-%s.json", name)
+}
+
+// UseProfile selects the named profile and overrides the settings with its values.
+// It returns an error if no such profile is defined.
+func (c *Config) UseProfile(name string) error {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile not found: %q", name)
+	}
+	if p.CompanyID != 0 {
+		c.Freee.CompanyID = p.CompanyID
+	}
+	c.OAuth2.TokenFile = p.TokenFileOrDefault(name)
+	// Resolve the token file of the profile relative to the file that defines the profile.
+	c.origins = maps.Clone(c.origins)
+	if c.origins == nil {
+		c.origins = make(Origins)
+	}
+	if origin := c.profileOrigin(name); origin != OriginDefault {
+		c.origins["oauth2.token_file"] = origin
+	} else {
+		delete(c.origins, "oauth2.token_file")
+	}
+	c.activeProfile = name
+	return nil
+}
+
+// profileOrigin returns the config file that sets the token file of the named
+// profile. If the token file is not set, it returns the file that defines the
+// profile, so that the default token file is placed next to it.
+func (c *Config) profileOrigin(name string) string {
+	for _, key := range []string{"token_file", "company_id", "client_id_env", "client_secret_env"} {
+		if origin, ok := c.origins[formatTableName([]string{"profiles", name, key})]; ok {
+			return origin
+		}
+	}
+	return OriginDefault
+}
+
+// ActiveProfile returns the name and settings of the profile selected by UseProfile.
+// ok is false if no profile has been selected.
+func (c *Config) ActiveProfile() (name string, p Profile, ok bool) {
+	if c.activeProfile == "" {
+		return "", Profile{}, false
+	}
+	return c.activeProfile, c.Profiles[c.activeProfile], true
+}
+
+// ProfileNames returns the names of all defined profiles in sorted order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
 }
 
 func (c *Config) Marshal() ([]byte, error) {
@@ -47,6 +169,7 @@ func Default() *Config {
 	return cfg
 }
 
+// InitConfigFile creates the config file with the content of config.example.toml.
 func InitConfigFile() error {
 	configPath := ConfigPath()
 	configDir := filepath.Dir(configPath)
@@ -55,56 +178,62 @@ func InitConfigFile() error {
 		return fmt.Errorf("create config directory: %w", err)
 	}
 
-	defaultConfig := Default()
-	data, err := toml.Marshal(defaultConfig)
-	if err != nil {
-		panic(fmt.Sprintf("marshal default config: %v", err)) // should not happen
-	}
-
-	if err := os.WriteFile(configPath, data, 0o644); err != nil {
+	// Write the example as is, so that the comments are available to the user.
+	if err := os.WriteFile(configPath, defaultConfig, 0o644); err != nil {
 		return fmt.Errorf("write config file: %w", err)
 	}
 
 	return nil
 }
 
+// explicitPath is the config file path given by SetConfigPath
+var explicitPath string
+
+// SetConfigPath sets the path of the config file explicitly.
+// Once set, Load and ConfigPath use this path instead of searching the XDG directories.
+// An empty path restores the default behavior.
+func SetConfigPath(path string) error {
+	if path == "" {
+		explicitPath = ""
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolve config path: %w", err)
+	}
+	explicitPath = abs
+	return nil
+}
+
 // Load loads configuration from file following XDG Base Directory specification
 // It searches for config.toml in the following order:
 // 1. $XDG_CONFIG_HOME/ffbox/config.toml
 // 2. $HOME/.config/ffbox/config.toml
 //
+// In addition, the nearest project-local config file (.ffbox.toml) found by
+// walking up from the working directory is merged over it, field by field.
+// See LoadWithOrigins for details.
+//
 // If no config file is found, it returns the default configuration.
+// If the path is given by SetConfigPath, only that file is loaded and
+// it is an error if the file does not exist.
 func Load() (*Config, error) {
-	configPath, err := findConfigFile()
-	if err != nil {
-		return nil, fmt.Errorf("no config file found: %w", err)
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("read config file: %w", err)
-	}
-
-	cfg := Default()
-	if err := toml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("parse config file: %w", err)
-	}
-
-	return cfg, nil
+	cfg, _, err := LoadWithOrigins()
+	return cfg, err
 }
 
 // findConfigFile searches for config.toml following XDG Base Directory specification
-func findConfigFile() (string, error) {
+func findConfigFile() (string, bool) {
 	configDirs := getConfigDirs()
 
 	for _, dir := range configDirs {
 		configPath := filepath.Join(dir, appName, configFileName)
 		if _, err := os.Stat(configPath); err == nil {
-			return configPath, nil
+			return configPath, true
 		}
 	}
 
-	return "", fmt.Errorf("config file not found")
+	return "", false
 }
 
 // getConfigDirs returns config directories in order of preference
@@ -125,12 +254,46 @@ func getConfigDirs() []string {
 	return dirs
 }
 
-// ConfigPath returns the expected config file path
+// ConfigPath returns the config file path.
+// It is the path given by SetConfigPath if any, otherwise the config file found
+// in the XDG directories, or the expected path if none exists yet.
 // This can be used to inform users where to place the config file
 func ConfigPath() string {
+	if explicitPath != "" {
+		return explicitPath
+	}
+	if found, ok := findConfigFile(); ok {
+		return found
+	}
 	dirs := getConfigDirs()
 	if len(dirs) > 0 {
 		return filepath.Join(dirs[0], appName, configFileName)
 	}
 	return configFileName
 }
+
+// CacheDir returns the directory for cached data following XDG Base Directory
+// specification: $XDG_CACHE_HOME/ffbox, or $HOME/.cache/ffbox.
+// It returns an empty string if neither is available.
+func CacheDir() string {
+	if xdgCacheHome := os.Getenv("XDG_CACHE_HOME"); xdgCacheHome != "" {
+		return filepath.Join(xdgCacheHome, appName)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".cache", appName)
+	}
+	return ""
+}
+
+// StateDir returns the directory for persistent state data following XDG Base
+// Directory specification: $XDG_STATE_HOME/ffbox, or $HOME/.local/state/ffbox.
+// It returns an empty string if neither is available.
+func StateDir() string {
+	if xdgStateHome := os.Getenv("XDG_STATE_HOME"); xdgStateHome != "" {
+		return filepath.Join(xdgStateHome, appName)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", appName)
+	}
+	return ""
+}
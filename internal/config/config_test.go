@@ -0,0 +1,305 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestUseProfile(t *testing.T) {
+	cfg := Default()
+	cfg.Freee.CompanyID = 100
+	cfg.Profiles = map[string]Profile{
+		"work": {CompanyID: 200, ClientIDEnv: "WORK_ID", ClientSecretEnv: "WORK_SECRET", TokenFile: "work.json"},
+		"home": {},
+	}
+
+	t.Run("overrides settings with profile values", func(t *testing.T) {
+		c := *cfg
+		if err := c.UseProfile("work"); err != nil {
+			t.Fatalf("UseProfile() error = %v", err)
+		}
+		if c.Freee.CompanyID != 200 {
+			t.Errorf("CompanyID = %d, want 200", c.Freee.CompanyID)
+		}
+		if c.OAuth2.TokenFile != "work.json" {
+			t.Errorf("TokenFile = %q, want %q", c.OAuth2.TokenFile, "work.json")
+		}
+		name, p, ok := c.ActiveProfile()
+		if !ok || name != "work" || p.ClientIDEnv != "WORK_ID" {
+			t.Errorf("ActiveProfile() = %q, %+v, %v", name, p, ok)
+		}
+	})
+
+	t.Run("keeps company ID and uses default token file when unset", func(t *testing.T) {
+		c := *cfg
+		if err := c.UseProfile("home"); err != nil {
+			t.Fatalf("UseProfile() error = %v", err)
+		}
+		if c.Freee.CompanyID != 100 {
+			t.Errorf("CompanyID = %d, want 100", c.Freee.CompanyID)
+		}
+		if c.OAuth2.TokenFile != "token-home.json" {
+			t.Errorf("TokenFile = %q, want %q", c.OAuth2.TokenFile, "token-home.json")
+		}
+	})
+
+	t.Run("returns error for unknown profile", func(t *testing.T) {
+		c := *cfg
+		if err := c.UseProfile("unknown"); err == nil {
+			t.Error("UseProfile() error = nil, want error")
+		}
+		if _, _, ok := c.ActiveProfile(); ok {
+			t.Error("ActiveProfile() ok = true, want false")
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("example config has no problems", func(t *testing.T) {
+		if problems := Validate(defaultConfig); len(problems) != 0 {
+			t.Errorf("Validate(config.example.toml) = %v, want no problems", problems)
+		}
+	})
+
+	t.Run("reports unknown keys and bad values", func(t *testing.T) {
+		doc := `unknown = 1
+[freee]
+company_id = "123"
+[oauth2]
+local_addr = "localhost"
+[profiles.work]
+company_id = 1
+client_id = "x"
+`
+		var got []string
+		for _, p := range Validate([]byte(doc)) {
+			got = append(got, p.Key)
+		}
+		want := []string{"freee.company_id", "oauth2.local_addr", "profiles.work.client_id", "unknown"}
+		if !slices.Equal(got, want) {
+			t.Errorf("Validate() keys = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("reports syntax errors", func(t *testing.T) {
+		problems := Validate([]byte("[freee"))
+		if len(problems) != 1 || problems[0].Key != "" {
+			t.Errorf("Validate() = %v, want a syntax error", problems)
+		}
+	})
+}
+
+func TestConfigGet(t *testing.T) {
+	cfg := Default()
+	cfg.Profiles = map[string]Profile{"work": {CompanyID: 200}}
+
+	tests := []struct {
+		key     string
+		want    any
+		wantErr bool
+	}{
+		{key: "oauth2.local_addr", want: ":3485"},
+		{key: "freee.company_id", want: int64(0)},
+		{key: "profiles.work.company_id", want: int64(200)},
+		{key: "profiles.home.company_id", wantErr: true},
+		{key: "freee.unknown", wantErr: true},
+		{key: "freee", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := cfg.Get(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Get(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadExplicitPath(t *testing.T) {
+	t.Cleanup(func() { SetConfigPath("") })
+	dir := t.TempDir()
+
+	t.Run("loads the given file", func(t *testing.T) {
+		path := filepath.Join(dir, "ffbox.toml")
+		if err := os.WriteFile(path, []byte("[freee]\ncompany_id = 42\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetConfigPath(path); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if cfg.Freee.CompanyID != 42 {
+			t.Errorf("CompanyID = %d, want 42", cfg.Freee.CompanyID)
+		}
+		if ConfigPath() != path {
+			t.Errorf("ConfigPath() = %q, want %q", ConfigPath(), path)
+		}
+	})
+
+	t.Run("missing file is an error", func(t *testing.T) {
+		if err := SetConfigPath(filepath.Join(dir, "missing.toml")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(); err == nil {
+			t.Error("Load() error = nil, want error")
+		}
+	})
+
+	t.Run("broken file is an error", func(t *testing.T) {
+		path := filepath.Join(dir, "broken.toml")
+		if err := os.WriteFile(path, []byte("[freee"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetConfigPath(path); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(); err == nil {
+			t.Error("Load() error = nil, want error")
+		}
+	})
+}
+
+func TestLoadWithOrigins(t *testing.T) {
+	userDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", userDir)
+	userConfig := filepath.Join(userDir, appName, configFileName)
+	if err := os.MkdirAll(filepath.Dir(userConfig), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(userConfig, []byte(`[freee]
+company_id = 1
+[oauth2]
+local_addr = ":9999"
+[audit]
+log_file = "audit.jsonl"
+[profiles.work]
+company_id = 10
+token_file = "work.json"
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Resolve symlinks so that the path matches the one derived from os.Getwd.
+	projectDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectConfig := filepath.Join(projectDir, projectConfigFileName)
+	err = os.WriteFile(projectConfig, []byte(`[freee]
+company_id = 2
+[classify]
+rules_file = "rules/project.toml"
+[profiles.work]
+company_id = 20
+[profiles.project]
+company_id = 30
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workDir := filepath.Join(projectDir, "a", "b")
+	if err := os.MkdirAll(workDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(workDir)
+
+	cfg, origins, err := LoadWithOrigins()
+	if err != nil {
+		t.Fatalf("LoadWithOrigins() error = %v", err)
+	}
+	tests := []struct {
+		key        string
+		want       any
+		wantOrigin string
+	}{
+		{"freee.company_id", int64(2), projectConfig},
+		{"oauth2.local_addr", ":9999", userConfig},
+		{"oauth2.token_file", "token.json", OriginDefault},
+		{"profiles.work.company_id", int64(20), projectConfig},
+		{"profiles.work.token_file", "work.json", userConfig},
+	}
+	for _, tt := range tests {
+		got, err := cfg.Get(tt.key)
+		if err != nil {
+			t.Fatalf("Get(%q) error = %v", tt.key, err)
+		}
+		if got != tt.want {
+			t.Errorf("Get(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+		if origin := origins.Of(tt.key); origin != tt.wantOrigin {
+			t.Errorf("Origins.Of(%q) = %q, want %q", tt.key, origin, tt.wantOrigin)
+		}
+	}
+
+	// Relative paths are resolved against the file each value came from.
+	paths := []struct {
+		key, path, want string
+	}{
+		{"classify.rules_file", cfg.Classify.RulesFile, filepath.Join(projectDir, "rules", "project.toml")},
+		{"audit.log_file", cfg.Audit.LogFile, filepath.Join(userDir, appName, "audit.jsonl")},
+		{"oauth2.token_file", cfg.OAuth2.TokenFile, filepath.Join(userDir, appName, "token.json")},
+		{"audit.log_file", filepath.Join(projectDir, "audit.jsonl"), filepath.Join(projectDir, "audit.jsonl")},
+	}
+	for _, tt := range paths {
+		if got := cfg.ResolvePath(tt.key, tt.path); got != tt.want {
+			t.Errorf("ResolvePath(%q, %q) = %q, want %q", tt.key, tt.path, got, tt.want)
+		}
+	}
+
+	// The token file of a profile is relative to the file that defines it.
+	profiles := []struct {
+		name, want string
+	}{
+		{"work", filepath.Join(userDir, appName, "work.json")},
+		{"project", filepath.Join(projectDir, "token-project.json")},
+	}
+	for _, tt := range profiles {
+		c := *cfg
+		if err := c.UseProfile(tt.name); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.ResolvePath("oauth2.token_file", c.OAuth2.TokenFile); got != tt.want {
+			t.Errorf("token file of profile %s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := origins.Of("oauth2.token_file"); got != OriginDefault {
+		t.Errorf("UseProfile() changed the loaded origins: %q", got)
+	}
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	t.Cleanup(func() { SetConfigPath("") })
+	path := filepath.Join(t.TempDir(), "ffbox.toml")
+	if err := os.WriteFile(path, []byte("[freee]\napi_endpoint = \"http://file.example/\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetConfigPath(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FFBOX_API_ENDPOINT", "http://127.0.0.1:8089/")
+	t.Setenv("FFBOX_OAUTH2_TOKEN_URL", "")
+
+	cfg, origins, err := LoadWithOrigins()
+	if err != nil {
+		t.Fatalf("LoadWithOrigins() error = %v", err)
+	}
+	if got, want := cfg.Freee.APIEndpoint, "http://127.0.0.1:8089/"; got != want {
+		t.Errorf("APIEndpoint = %q, want %q", got, want)
+	}
+	if got, want := origins.Of("freee.api_endpoint"), "$FFBOX_API_ENDPOINT"; got != want {
+		t.Errorf("origin of freee.api_endpoint = %q, want %q", got, want)
+	}
+	// Empty variables are ignored.
+	if got, want := cfg.OAuth2.TokenURL, Default().OAuth2.TokenURL; got != want {
+		t.Errorf("TokenURL = %q, want %q", got, want)
+	}
+}
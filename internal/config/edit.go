@@ -0,0 +1,261 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WriteCompanyID writes the default company ID into the config file.
+// If profile is not empty, the value is written into the [profiles.<profile>] table
+// instead of the [freee] table.
+func WriteCompanyID(profile string, id int64) error {
+	key := "freee.company_id"
+	if profile != "" {
+		key = formatTableName([]string{"profiles", profile, "company_id"})
+	}
+	return Set(key, strconv.FormatInt(id, 10))
+}
+
+// updateConfigFile applies fn to the content of the config file and writes it back.
+// If the config file does not exist yet, it is created from config.example.toml first.
+func updateConfigFile(fn func(doc []byte) []byte) error {
+	configPath := ConfigPath()
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if err := InitConfigFile(); err != nil {
+			return err
+		}
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	data = fn(data)
+
+	// Write to a temporary file first so that the config is never left half-written.
+	tmp, err := os.CreateTemp(filepath.Dir(configPath), "."+configFileName+".*")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), configPath); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	return nil
+}
+
+// setKey sets key to the TOML literal value in the given table of doc.
+//
+// This is a line-oriented editor that only understands the subset of TOML
+// used by the config file: [table] headers and single-line key = value pairs.
+// Comments and blank lines are kept untouched.
+//
+//   - If the key exists in the table, the value is replaced in place.
+//   - If the table exists but the key does not, the key is appended to the table.
+//   - If the table does not exist, it is appended to the end of doc.
+func setKey(doc []byte, table []string, key, literal string) []byte {
+	lines := splitLines(doc)
+	start, end, found := findTable(lines, table)
+	if !found {
+		var b bytes.Buffer
+		b.Write(doc)
+		if len(doc) > 0 && !bytes.HasSuffix(doc, []byte("\n")) {
+			b.WriteString("\n")
+		}
+		if len(doc) > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\n%s = %s\n", formatTableName(table), key, literal)
+		return b.Bytes()
+	}
+
+	lastKeyLine := start
+	for i := start + 1; i < end; i++ {
+		k, ok := parseKeyLine(lines[i])
+		if !ok {
+			continue
+		}
+		lastKeyLine = i
+		if k != key {
+			continue
+		}
+		indent := lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))]
+		newLine := fmt.Sprintf("%s%s = %s", indent, key, literal)
+		if comment := trailingComment(lines[i]); comment != "" {
+			newLine += " " + comment
+		}
+		lines[i] = newLine
+		return joinLines(lines)
+	}
+
+	// Insert the new key just after the last key of the table, so that it
+	// does not get separated from the table by the comments of the next one.
+	insertAt := lastKeyLine + 1
+	if lastKeyLine == start {
+		// Skip blank lines right after the header to keep the layout.
+		for insertAt < end && strings.TrimSpace(lines[insertAt]) == "" {
+			insertAt++
+		}
+	}
+	newLine := fmt.Sprintf("%s = %s", key, literal)
+	lines = append(lines[:insertAt], append([]string{newLine}, lines[insertAt:]...)...)
+	return joinLines(lines)
+}
+
+// unsetKey removes key from the given table of doc.
+// Comments following the key line are kept, since they cannot be told apart
+// from those of the next key reliably.
+func unsetKey(doc []byte, table []string, key string) []byte {
+	lines := splitLines(doc)
+	start, end, found := findTable(lines, table)
+	if !found {
+		return doc
+	}
+	for i := start + 1; i < end; i++ {
+		if k, ok := parseKeyLine(lines[i]); ok && k == key {
+			return joinLines(slices.Delete(lines, i, i+1))
+		}
+	}
+	return doc
+}
+
+// findTable returns the line range of the given table in lines.
+// start is the index of the header line (or -1 for the root table) and
+// end is the index of the next header line or len(lines).
+func findTable(lines []string, table []string) (start, end int, found bool) {
+	start = -1
+	if len(table) == 0 {
+		found = true
+	}
+	for i, line := range lines {
+		name, ok := parseTableHeader(line)
+		if !ok {
+			continue
+		}
+		if found {
+			return start, i, true
+		}
+		if slices.Equal(name, table) {
+			start, found = i, true
+		}
+	}
+	return start, len(lines), found
+}
+
+// parseTableHeader parses a "[a.b]" header line and returns its name parts.
+func parseTableHeader(line string) ([]string, bool) {
+	s := strings.TrimSpace(line)
+	if comment := trailingComment(s); comment != "" {
+		s = strings.TrimSpace(strings.TrimSuffix(s, comment))
+	}
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") || strings.HasPrefix(s, "[[") {
+		return nil, false
+	}
+	return splitDottedKey(s[1 : len(s)-1]), true
+}
+
+// parseKeyLine returns the key of a "key = value" line.
+func parseKeyLine(line string) (string, bool) {
+	s := strings.TrimSpace(line)
+	if s == "" || strings.HasPrefix(s, "#") || strings.HasPrefix(s, "[") {
+		return "", false
+	}
+	k, _, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", false
+	}
+	return strings.Trim(strings.TrimSpace(k), `"'`), true
+}
+
+// trailingComment returns the "# ..." part of line that is outside of any string.
+func trailingComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+// splitDottedKey splits a dotted TOML key such as `profiles."my co"` into parts.
+func splitDottedKey(s string) []string {
+	var (
+		parts []string
+		cur   strings.Builder
+		quote rune
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			cur.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '.':
+			parts = append(parts, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		case r == ' ' || r == '\t':
+			// ignore whitespace around dots
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	return append(parts, strings.TrimSpace(cur.String()))
+}
+
+// formatTableName formats table name parts, quoting those that are not bare keys.
+func formatTableName(table []string) string {
+	parts := make([]string, len(table))
+	for i, p := range table {
+		parts[i] = formatKey(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+func formatKey(k string) string {
+	if k == "" {
+		return `""`
+	}
+	for _, r := range k {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return fmt.Sprintf("%q", k)
+		}
+	}
+	return k
+}
+
+func splitLines(doc []byte) []string {
+	if len(doc) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(doc), "\n"), "\n")
+}
+
+func joinLines(lines []string) []byte {
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,142 @@
+package config
+
+import "testing"
+
+func TestSetKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		table   []string
+		key     string
+		literal string
+		want    string
+	}{
+		{
+			name: "replaces existing value and keeps comments",
+			doc: `[freee]
+
+company_id = 0 # default company
+# freee 事業者IDを指定します
+
+[oauth2]
+token_file = "token.json"
+`,
+			table:   []string{"freee"},
+			key:     "company_id",
+			literal: "123",
+			want: `[freee]
+
+company_id = 123 # default company
+# freee 事業者IDを指定します
+
+[oauth2]
+token_file = "token.json"
+`,
+		},
+		{
+			name: "does not touch the same key in another table",
+			doc: `[profiles.work]
+company_id = 1
+
+[freee]
+company_id = 2
+`,
+			table:   []string{"profiles", "work"},
+			key:     "company_id",
+			literal: "3",
+			want: `[profiles.work]
+company_id = 3
+
+[freee]
+company_id = 2
+`,
+		},
+		{
+			name: "appends missing key after the last key of the table",
+			doc: `[oauth2]
+token_file = "token.json"
+# comment for token_file
+
+[freee]
+`,
+			table:   []string{"oauth2"},
+			key:     "local_addr",
+			literal: `":3485"`,
+			want: `[oauth2]
+token_file = "token.json"
+local_addr = ":3485"
+# comment for token_file
+
+[freee]
+`,
+		},
+		{
+			name: "appends missing table",
+			doc: `[freee]
+company_id = 1
+`,
+			table:   []string{"profiles", "my co"},
+			key:     "company_id",
+			literal: "2",
+			want: `[freee]
+company_id = 1
+
+[profiles."my co"]
+company_id = 2
+`,
+		},
+		{
+			name:    "creates table in empty document",
+			doc:     "",
+			table:   []string{"freee"},
+			key:     "company_id",
+			literal: "1",
+			want: `[freee]
+company_id = 1
+`,
+		},
+		{
+			name: "matches quoted table names",
+			doc: `[profiles."my co"] # comment
+company_id = 1
+`,
+			table:   []string{"profiles", "my co"},
+			key:     "company_id",
+			literal: "2",
+			want: `[profiles."my co"] # comment
+company_id = 2
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(setKey([]byte(tt.doc), tt.table, tt.key, tt.literal))
+			if got != tt.want {
+				t.Errorf("setKey() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsetKey(t *testing.T) {
+	doc := `[oauth2]
+token_file = "token.json"
+local_addr = ":3485"
+
+[freee]
+local_addr = "keep"
+`
+	want := `[oauth2]
+token_file = "token.json"
+
+[freee]
+local_addr = "keep"
+`
+	got := string(unsetKey([]byte(doc), []string{"oauth2"}, "local_addr"))
+	if got != want {
+		t.Errorf("unsetKey() =\n%s\nwant:\n%s", got, want)
+	}
+	if got := string(unsetKey([]byte(doc), []string{"profiles", "x"}, "local_addr")); got != doc {
+		t.Errorf("unsetKey() on missing table changed the document:\n%s", got)
+	}
+}
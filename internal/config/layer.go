@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// OriginDefault is the origin of values that are not set in any config file.
+const OriginDefault = "(default)"
+
+// Origins maps dotted keys, such as "freee.company_id", to the path of the
+// config file that the effective value came from.
+type Origins map[string]string
+
+// Of returns the origin of the dotted key. It returns OriginDefault
+// for keys that are not set in any config file.
+func (o Origins) Of(key string) string {
+	if origin, ok := o[key]; ok {
+		return origin
+	}
+	return OriginDefault
+}
+
+// LoadWithOrigins loads configuration like Load, and also returns which file
+// each effective value came from.
+//
+// The config files are layered in the following order, later ones taking precedence:
+//
+//  1. The default configuration
+//  2. The user config file ($XDG_CONFIG_HOME/ffbox/config.toml etc.)
+//  3. The nearest .ffbox.toml found by walking up from the working directory
+//  4. The environment variables listed in EnvOverrides
+//
+// Each layer overrides the previous ones field by field, so a project-local
+// config only needs to contain the values that differ from the user config.
+// If the path is given by SetConfigPath, only that file is loaded.
+func LoadWithOrigins() (*Config, Origins, error) {
+	var paths []string
+	if explicitPath != "" {
+		paths = append(paths, explicitPath)
+	} else {
+		if found, ok := findConfigFile(); ok {
+			paths = append(paths, found)
+		}
+		if found, ok := ProjectConfigPath(); ok {
+			paths = append(paths, found)
+		}
+	}
+
+	merged := make(map[string]any)
+	origins := make(Origins)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read config file: %w", err)
+		}
+		var layer map[string]any
+		if err := toml.Unmarshal(data, &layer); err != nil {
+			return nil, nil, fmt.Errorf("parse config file %q: %w", path, err)
+		}
+		mergeTable(merged, layer, nil, path, origins)
+	}
+
+	envLayer := make(map[string]any)
+	for _, o := range EnvOverrides {
+		v, ok := os.LookupEnv(o.Env)
+		if !ok || v == "" {
+			continue
+		}
+		path := splitDottedKey(o.Key)
+		table := envLayer
+		for _, name := range path[:len(path)-1] {
+			sub, ok := table[name].(map[string]any)
+			if !ok {
+				sub = make(map[string]any)
+				table[name] = sub
+			}
+			table = sub
+		}
+		table[path[len(path)-1]] = v
+		mergeTable(merged, envLayer, nil, "$"+o.Env, origins)
+		clear(envLayer)
+	}
+
+	cfg := Default()
+	cfg.origins = origins
+	if len(merged) == 0 {
+		return cfg, origins, nil
+	}
+	data, err := toml.Marshal(merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("merge config files: %w", err)
+	}
+	if err := toml.Unmarshal(data, cfg); err != nil {
+		return nil, nil, fmt.Errorf("parse config file %q: %w", paths[len(paths)-1], err)
+	}
+	return cfg, origins, nil
+}
+
+// ResolvePath resolves path, the value of the dotted key, as a file path.
+// A relative path is taken relative to the directory of the config file the
+// value came from, so that a path set in a project-local .ffbox.toml is
+// relative to that file. Relative paths from the defaults or environment
+// variables are taken relative to the directory of ConfigPath.
+func (c *Config) ResolvePath(key, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	origin := c.origins.Of(key)
+	if origin == OriginDefault || strings.HasPrefix(origin, "$") {
+		origin = ConfigPath()
+	}
+	return filepath.Join(filepath.Dir(origin), path)
+}
+
+// EnvOverride is an environment variable that overrides a string value of the config files.
+type EnvOverride struct {
+	// Key is the dotted key to override
+	Key string
+	// Env is the name of the environment variable
+	Env string
+}
+
+// EnvOverrides lists the environment variables that take precedence over the config files.
+var EnvOverrides = []EnvOverride{
+	{Key: "freee.api_endpoint", Env: "FFBOX_API_ENDPOINT"},
+	{Key: "oauth2.auth_url", Env: "FFBOX_OAUTH2_AUTH_URL"},
+	{Key: "oauth2.token_url", Env: "FFBOX_OAUTH2_TOKEN_URL"},
+}
+
+// mergeTable merges src into dst recursively and records the origin of each leaf value.
+func mergeTable(dst, src map[string]any, prefix []string, origin string, origins Origins) {
+	for name, value := range src {
+		path := append(slices.Clone(prefix), name)
+		if sub, ok := value.(map[string]any); ok {
+			d, ok := dst[name].(map[string]any)
+			if !ok {
+				d = make(map[string]any)
+				dst[name] = d
+			}
+			mergeTable(d, sub, path, origin, origins)
+			continue
+		}
+		dst[name] = value
+		origins[formatTableName(path)] = origin
+	}
+}
+
+// ProjectConfigPath returns the path of the nearest project-local config file
+// (.ffbox.toml), searching from the working directory up to the root directory.
+func ProjectConfigPath() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, projectConfigFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// ResolvedKeys returns all dotted keys of c, with the names of the defined
+// profiles in place of the wildcards.
+func (c *Config) ResolvedKeys() []string {
+	var keys []string
+	var profileKeys [][]string
+	for _, k := range schema {
+		if slices.Contains(k.pattern, wildcard) {
+			profileKeys = append(profileKeys, k.pattern)
+			continue
+		}
+		keys = append(keys, strings.Join(k.pattern, "."))
+	}
+	// Only profiles is a map table for now.
+	for _, name := range c.ProfileNames() {
+		for _, pattern := range profileKeys {
+			path := slices.Clone(pattern)
+			path[slices.Index(path, wildcard)] = name
+			keys = append(keys, formatTableName(path))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,336 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// wildcard is the key part that matches any name of a map table, such as profiles.<name>.
+const wildcard = "*"
+
+// keySchema describes a configurable key derived from the toml tags of [Config].
+type keySchema struct {
+	// pattern is the dotted key path, where map keys are represented by [wildcard]
+	pattern []string
+	// kind is the kind of the value
+	kind reflect.Kind
+	// index is the field index path in Config; map lookups are marked with -1
+	index []int
+}
+
+// schema is the list of all configurable keys.
+var schema = buildSchema(reflect.TypeFor[Config](), nil, nil)
+
+// validators holds additional validation rules for values, keyed by pattern.
+var validators = map[string]func(string) error{
+	"oauth2.local_addr": func(v string) error {
+		if _, _, err := net.SplitHostPort(v); err != nil {
+			return fmt.Errorf("host:port 形式で指定してください: %w", err)
+		}
+		return nil
+	},
+	"oauth2.token_file":     nonEmpty,
+	"oauth2.auth_url":       httpURL,
+	"oauth2.token_url":      httpURL,
+	"freee.company_id":      nonNegative,
+	"freee.api_endpoint":    httpURL,
+	"deal.account_item_id":  nonNegative,
+	"deal.tax_code":         nonNegative,
+	"profiles.*.company_id": nonNegative,
+}
+
+func httpURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("http(s) の URL を指定してください: %q", v)
+	}
+	return nil
+}
+
+func nonEmpty(v string) error {
+	if v == "" {
+		return fmt.Errorf("空の値は指定できません")
+	}
+	return nil
+}
+
+func nonNegative(v string) error {
+	if strings.HasPrefix(v, "-") {
+		return fmt.Errorf("0 以上の値を指定してください")
+	}
+	return nil
+}
+
+func buildSchema(t reflect.Type, prefix []string, index []int) []keySchema {
+	var keys []keySchema
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		path := append(slices.Clone(prefix), name)
+		idx := append(slices.Clone(index), i)
+		switch f.Type.Kind() {
+		case reflect.Struct:
+			keys = append(keys, buildSchema(f.Type, path, idx)...)
+		case reflect.Map:
+			keys = append(keys, buildSchema(f.Type.Elem(), append(path, wildcard), append(idx, -1))...)
+		default:
+			keys = append(keys, keySchema{pattern: path, kind: f.Type.Kind(), index: idx})
+		}
+	}
+	return keys
+}
+
+// Keys returns all configurable keys. Names of map tables are shown as "*".
+func Keys() []string {
+	keys := make([]string, len(schema))
+	for i, k := range schema {
+		keys[i] = strings.Join(k.pattern, ".")
+	}
+	return keys
+}
+
+// lookupKey returns the schema of the dotted key, such as "profiles.work.company_id".
+func lookupKey(key string) (*keySchema, []string, error) {
+	path := splitDottedKey(key)
+	for i, k := range schema {
+		if matchPattern(k.pattern, path) {
+			return &schema[i], path, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown key: %q", key)
+}
+
+func matchPattern(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != wildcard && pattern[i] != path[i] {
+			return false
+		}
+		if pattern[i] == wildcard && path[i] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseValue checks raw against the schema and returns it as a TOML literal.
+func (k *keySchema) parseValue(raw string) (string, error) {
+	var literal string
+	switch k.kind {
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("整数を指定してください: %q", raw)
+		}
+		literal = strconv.FormatInt(n, 10)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "", fmt.Errorf("true または false を指定してください: %q", raw)
+		}
+		literal = strconv.FormatBool(b)
+	case reflect.String:
+		literal = quoteString(raw)
+	default:
+		return "", fmt.Errorf("unsupported value kind: %s", k.kind) // should not happen
+	}
+	if validate, ok := validators[strings.Join(k.pattern, ".")]; ok {
+		if err := validate(raw); err != nil {
+			return "", err
+		}
+	}
+	return literal, nil
+}
+
+// quoteString returns s as a TOML basic string.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Get returns the value of the dotted key in cfg.
+func (c *Config) Get(key string) (any, error) {
+	k, path, err := lookupKey(key)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(c).Elem()
+	for i, idx := range k.index {
+		if idx >= 0 {
+			v = v.Field(idx)
+			continue
+		}
+		v = v.MapIndex(reflect.ValueOf(path[i]))
+		if !v.IsValid() {
+			return nil, fmt.Errorf("%q is not defined", strings.Join(path[:i+1], "."))
+		}
+	}
+	return v.Interface(), nil
+}
+
+// Set writes the value of the dotted key into the config file.
+// The value is checked against the type of the corresponding field of [Config].
+//
+// The rest of the file, including comments, is kept as is.
+// If the config file does not exist yet, it is created from config.example.toml first.
+func Set(key, value string) error {
+	k, path, err := lookupKey(key)
+	if err != nil {
+		return err
+	}
+	literal, err := k.parseValue(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return updateConfigFile(func(doc []byte) []byte {
+		return setKey(doc, path[:len(path)-1], path[len(path)-1], literal)
+	})
+}
+
+// Unset removes the dotted key from the config file, so that the default value is used.
+func Unset(key string) error {
+	_, path, err := lookupKey(key)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(ConfigPath()); os.IsNotExist(err) {
+		return nil // nothing to unset
+	}
+	return updateConfigFile(func(doc []byte) []byte {
+		return unsetKey(doc, path[:len(path)-1], path[len(path)-1])
+	})
+}
+
+// Problem is an issue found in a config file by [Validate].
+type Problem struct {
+	// Key is the dotted key path of the problematic entry, empty for syntax errors
+	Key string
+	// Message describes the problem
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Key == "" {
+		return p.Message
+	}
+	return fmt.Sprintf("%s: %s", p.Key, p.Message)
+}
+
+// Validate checks the TOML document data against the schema of [Config].
+// It reports syntax errors, unknown keys and values of a wrong type or out of range.
+func Validate(data []byte) []Problem {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return []Problem{{Message: fmt.Sprintf("syntax error: %v", err)}}
+	}
+	var problems []Problem
+	validateTable(doc, nil, &problems)
+	slices.SortFunc(problems, func(a, b Problem) int { return strings.Compare(a.Key, b.Key) })
+	return problems
+}
+
+func validateTable(table map[string]any, prefix []string, problems *[]Problem) {
+	for name, value := range table {
+		path := append(slices.Clone(prefix), name)
+		key := formatTableName(path)
+		if sub, ok := value.(map[string]any); ok {
+			if !isTablePrefix(path) {
+				*problems = append(*problems, Problem{Key: key, Message: "unknown table"})
+				continue
+			}
+			validateTable(sub, path, problems)
+			continue
+		}
+		k := findSchema(path)
+		if k == nil {
+			*problems = append(*problems, Problem{Key: key, Message: "unknown key"})
+			continue
+		}
+		if err := k.checkValue(value); err != nil {
+			*problems = append(*problems, Problem{Key: key, Message: err.Error()})
+		}
+	}
+}
+
+func findSchema(path []string) *keySchema {
+	for i, k := range schema {
+		if matchPattern(k.pattern, path) {
+			return &schema[i]
+		}
+	}
+	return nil
+}
+
+// isTablePrefix reports whether path is a table of the schema.
+func isTablePrefix(path []string) bool {
+	for _, k := range schema {
+		if len(k.pattern) > len(path) && matchPattern(k.pattern[:len(path)], path) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkValue checks a decoded TOML value against the schema.
+func (k *keySchema) checkValue(v any) error {
+	var raw string
+	switch k.kind {
+	case reflect.Int, reflect.Int64:
+		n, ok := v.(int64)
+		if !ok {
+			return fmt.Errorf("整数を指定してください: %v", v)
+		}
+		raw = strconv.FormatInt(n, 10)
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("true または false を指定してください: %v", v)
+		}
+		raw = strconv.FormatBool(b)
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("文字列を指定してください: %v", v)
+		}
+		raw = s
+	}
+	if validate, ok := validators[strings.Join(k.pattern, ".")]; ok {
+		return validate(raw)
+	}
+	return nil
+}
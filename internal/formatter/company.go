@@ -0,0 +1,311 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+
+	freeeapigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// CompanySummary is an element of [freeeapigen.CompanyIndexResponse] Companies.
+type CompanySummary = struct {
+	CompanyNumber string                                        `json:"company_number"`
+	DisplayName   *string                                       `json:"display_name"`
+	Id            int64                                         `json:"id"`
+	Name          *string                                       `json:"name"`
+	NameKana      *string                                       `json:"name_kana"`
+	Role          freeeapigen.CompanyIndexResponseCompaniesRole `json:"role"`
+}
+
+// Company formats a company in a human-readable format similar to `gh pr view`.
+type Company struct {
+	w         io.Writer
+	defaultID int64
+}
+
+// NewCompany creates a new Company formatter.
+// The company whose ID equals defaultID is marked as the default company.
+func NewCompany(w io.Writer, defaultID int64) *Company {
+	return &Company{w: w, defaultID: defaultID}
+}
+
+// Format writes the company in a human-readable format.
+func (f *Company) Format(r *freeeapigen.CompanyResponse) error {
+	if r == nil {
+		return fmt.Errorf("company is nil")
+	}
+	c := &r.Company
+
+	var b strings.Builder
+
+	id := fmt.Sprintf("%d", c.Id)
+	if c.Id == f.defaultID {
+		id += " (default)"
+	}
+	fmt.Fprintf(&b, "ID:               %s\n", id)
+	fmt.Fprintf(&b, "Name:             %s\n", c.DisplayName)
+	fmt.Fprintf(&b, "Name (Kana):      %s\n", formatString(c.NameKana))
+	fmt.Fprintf(&b, "Role:             %s\n", c.Role)
+	fmt.Fprintf(&b, "Company Number:   %s\n", c.CompanyNumber)
+	fmt.Fprintf(&b, "Corporate Number: %s\n", orNone(c.CorporateNumber))
+
+	// Fiscal Years section
+	b.WriteString("\nFiscal Years\n")
+	if len(c.FiscalYears) == 0 {
+		b.WriteString("  (none)\n")
+	}
+	for _, fy := range c.FiscalYears {
+		fmt.Fprintf(&b, "  %s - %s\n", formatString(fy.StartDate), formatString(fy.EndDate))
+	}
+
+	_, err := f.w.Write([]byte(b.String()))
+	return err
+}
+
+// orNone returns "(none)" for an empty string.
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// ExtractCompanyFields extracts specified fields from a company and returns a map.
+// If fields is empty, returns all fields.
+func ExtractCompanyFields(c *CompanySummary, isDefault bool, fields []string) map[string]any {
+	result := make(map[string]any)
+
+	// If no fields specified, return all fields as a map
+	if len(fields) == 0 {
+		b, _ := json.Marshal(c)
+		json.Unmarshal(b, &result)
+		result["default"] = isDefault
+		return result
+	}
+
+	for _, field := range fields {
+		switch field {
+		case "id":
+			result["id"] = c.Id
+		case "display_name":
+			result["display_name"] = c.DisplayName
+		case "name":
+			result["name"] = c.Name
+		case "name_kana":
+			result["name_kana"] = c.NameKana
+		case "company_number":
+			result["company_number"] = c.CompanyNumber
+		case "role":
+			result["role"] = c.Role
+		case "default":
+			result["default"] = isDefault
+		}
+	}
+	return result
+}
+
+// CompanyList formats a list of companies in a table or CSV format.
+type CompanyList struct {
+	w         io.Writer
+	defaultID int64
+}
+
+// NewCompanyList creates a new CompanyList formatter.
+// The company whose ID equals defaultID is marked as the default company.
+func NewCompanyList(w io.Writer, defaultID int64) *CompanyList {
+	return &CompanyList{w: w, defaultID: defaultID}
+}
+
+// FormatWithFields writes the companies in a table format with only the specified fields.
+// If fields is empty, displays all default fields.
+func (f *CompanyList) FormatWithFields(companies []CompanySummary, fields []string) error {
+	if len(companies) == 0 {
+		return nil
+	}
+	selectedFields, err := determineCompanyFields(fields)
+	if err != nil {
+		return fmt.Errorf("determining company fields: %w", err)
+	}
+
+	header := make([]any, len(selectedFields))
+	headerAlignments := make([]tw.Align, len(selectedFields))
+	for i, fd := range selectedFields {
+		header[i] = fd.Header
+		headerAlignments[i] = fd.Alignment
+	}
+
+	rows := make([][]any, 0, len(companies))
+	for _, company := range companies {
+		row := make([]any, len(selectedFields))
+		for i, fd := range selectedFields {
+			row[i] = fd.Extractor(&company, company.Id == f.defaultID)
+		}
+		rows = append(rows, row)
+	}
+
+	table := tablewriter.NewTable(f.w,
+		tablewriter.WithConfig(tablewriter.Config{
+			Row: tw.CellConfig{Alignment: tw.CellAlignment{PerColumn: headerAlignments}},
+		}))
+	table.Header(header...)
+	table.Bulk(rows)
+	return table.Render()
+}
+
+// FormatCSV writes the companies in CSV format with only the specified fields.
+// The header row consists of the field names.
+func (f *CompanyList) FormatCSV(companies []CompanySummary, fields []string) error {
+	if len(fields) == 0 {
+		fields = slices.Clone(defaultCompanyFieldNames)
+	}
+	if _, err := determineCompanyFields(fields); err != nil {
+		return fmt.Errorf("determining company fields: %w", err)
+	}
+
+	w := csv.NewWriter(f.w)
+	if err := w.Write(fields); err != nil {
+		return err
+	}
+	for _, company := range companies {
+		values := ExtractCompanyFields(&company, company.Id == f.defaultID, fields)
+		record := make([]string, len(fields))
+		for i, field := range fields {
+			record[i] = csvValue(values[field])
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvValue formats a field value for CSV output. nil values become empty strings.
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case *string:
+		if v == nil {
+			return ""
+		}
+		return *v
+	case *int64:
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d", *v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// determineCompanyFields determines which fields to display based on requested fields.
+func determineCompanyFields(requestedFields []string) ([]companyFieldDef, error) {
+	if len(requestedFields) == 0 {
+		requestedFields = slices.Clone(defaultCompanyFieldNames)
+	}
+	selectedFields := make([]companyFieldDef, 0, len(requestedFields))
+	for i, fieldName := range requestedFields {
+		fd, ok := allCompanyFields[fieldName]
+		if !ok {
+			return nil, &UnsupportedFieldError{idx: uint(i), fieldName: fieldName}
+		}
+		selectedFields = append(selectedFields, fd)
+	}
+	return selectedFields, nil
+}
+
+// AvailableCompanyFields returns a list of all available company fields.
+func AvailableCompanyFields() []string {
+	return slices.Clone(allCompanyFieldNames)
+}
+
+// allCompanyFieldNames defines the available fields for companies.
+// this comes from [freeeapigen.CompanyIndexResponse]
+var allCompanyFieldNames = []string{
+	"default",
+	"id",
+	"company_number",
+	"display_name",
+	"name",
+	"name_kana",
+	"role",
+}
+
+// defaultCompanyFieldNames defines the default fields to display in company lists.
+var defaultCompanyFieldNames = []string{
+	"default",
+	"id",
+	"display_name",
+	"company_number",
+	"role",
+}
+
+type companyFieldDef struct {
+	Header    string
+	Alignment tw.Align
+	Extractor func(c *CompanySummary, isDefault bool) any
+}
+
+var allCompanyFields = map[string]companyFieldDef{
+	"default": {
+		Header:    "Default",
+		Alignment: tw.AlignCenter,
+		Extractor: func(_ *CompanySummary, isDefault bool) any {
+			if isDefault {
+				return "*"
+			}
+			return ""
+		},
+	},
+	"id": {
+		Header:    "ID",
+		Alignment: tw.AlignLeft,
+		Extractor: func(c *CompanySummary, _ bool) any {
+			return fmt.Sprintf("%d", c.Id)
+		},
+	},
+	"company_number": {
+		Header:    "Company Number",
+		Alignment: tw.AlignLeft,
+		Extractor: func(c *CompanySummary, _ bool) any {
+			return orNone(c.CompanyNumber)
+		},
+	},
+	"display_name": {
+		Header:    "Name",
+		Alignment: tw.AlignLeft,
+		Extractor: func(c *CompanySummary, _ bool) any {
+			return formatString(c.DisplayName)
+		},
+	},
+	"name": {
+		Header:    "Formal Name",
+		Alignment: tw.AlignLeft,
+		Extractor: func(c *CompanySummary, _ bool) any {
+			return formatString(c.Name)
+		},
+	},
+	"name_kana": {
+		Header:    "Name (Kana)",
+		Alignment: tw.AlignLeft,
+		Extractor: func(c *CompanySummary, _ bool) any {
+			return formatString(c.NameKana)
+		},
+	},
+	"role": {
+		Header:    "Role",
+		Alignment: tw.AlignLeft,
+		Extractor: func(c *CompanySummary, _ bool) any {
+			return string(c.Role)
+		},
+	},
+}
@@ -0,0 +1,71 @@
+package formatter
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// TestCompanyFieldDefinitionsConsistency ensures that allCompanyFieldNames,
+// defaultCompanyFieldNames, allCompanyFields and ExtractCompanyFields are consistent with each other.
+func TestCompanyFieldDefinitionsConsistency(t *testing.T) {
+	t.Run("allCompanyFieldNames and allCompanyFields have the same fields", func(t *testing.T) {
+		for fieldName := range allCompanyFields {
+			if !slices.Contains(allCompanyFieldNames, fieldName) {
+				t.Errorf("field %q exists in allCompanyFields but not in allCompanyFieldNames", fieldName)
+			}
+		}
+		for _, fieldName := range allCompanyFieldNames {
+			if _, ok := allCompanyFields[fieldName]; !ok {
+				t.Errorf("field %q exists in allCompanyFieldNames but not in allCompanyFields", fieldName)
+			}
+		}
+	})
+
+	t.Run("defaultCompanyFieldNames is a subset of allCompanyFieldNames", func(t *testing.T) {
+		for _, fieldName := range defaultCompanyFieldNames {
+			if !slices.Contains(allCompanyFieldNames, fieldName) {
+				t.Errorf("field %q exists in defaultCompanyFieldNames but not in allCompanyFieldNames", fieldName)
+			}
+		}
+	})
+
+	t.Run("ExtractCompanyFields supports all fields", func(t *testing.T) {
+		for _, fieldName := range allCompanyFieldNames {
+			got := ExtractCompanyFields(&CompanySummary{}, false, []string{fieldName})
+			if _, ok := got[fieldName]; !ok {
+				t.Errorf("field %q is not extracted by ExtractCompanyFields", fieldName)
+			}
+		}
+	})
+
+	t.Run("all companyFieldDef entries have required fields set", func(t *testing.T) {
+		for fieldName, fd := range allCompanyFields {
+			if fd.Header == "" {
+				t.Errorf("field %q has empty Header", fieldName)
+			}
+			if fd.Extractor == nil {
+				t.Errorf("field %q has nil Extractor", fieldName)
+			}
+		}
+	})
+}
+
+func TestCompanyListFormatCSV(t *testing.T) {
+	name := "株式会社ABC"
+	companies := []CompanySummary{
+		{Id: 1, DisplayName: &name, CompanyNumber: "1234567890", Role: "admin"},
+		{Id: 2, Role: "read_only"},
+	}
+	var buf bytes.Buffer
+	f := NewCompanyList(&buf, 2)
+	if err := f.FormatCSV(companies, []string{"id", "display_name", "role", "default"}); err != nil {
+		t.Fatalf("FormatCSV() error = %v", err)
+	}
+	want := "id,display_name,role,default\n" +
+		"1,株式会社ABC,admin,false\n" +
+		"2,,read_only,true\n"
+	if got := buf.String(); got != want {
+		t.Errorf("FormatCSV() =\n%s\nwant:\n%s", got, want)
+	}
+}
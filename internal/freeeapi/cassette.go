@@ -0,0 +1,308 @@
+package freeeapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Cassette is a recording of HTTP interactions with the freee API.
+// It is saved by Recorder and served by Replayer.
+//
+// Text bodies are stored inline. Binary bodies, such as receipt files, are
+// stored as separate files in the "<cassette>.files" directory next to the
+// cassette, and referenced by BodyFile relative to the cassette.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a pair of a request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request in a Cassette.
+type RecordedRequest struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     string      `json:"body,omitempty"`
+	BodyFile string      `json:"body_file,omitempty"`
+}
+
+// RecordedResponse is a response in a Cassette.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyFile   string      `json:"body_file,omitempty"`
+}
+
+// redacted replaces the values of sensitive headers in a Cassette.
+const redacted = "REDACTED"
+
+// sensitiveHeaders are the headers whose values are not saved in a Cassette.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// Recorder is an http.RoundTripper that saves every request and response
+// to a cassette file, so that they can be served later by Replayer.
+//
+// The cassette file is rewritten after each interaction, so that it is
+// usable even if the command fails halfway.
+type Recorder struct {
+	path string
+	base http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a Recorder that sends requests with base and saves them to path.
+// If base is nil, http.DefaultTransport is used.
+func NewRecorder(path string, base http.RoundTripper) *Recorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Recorder{path: path, base: base}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+		reqBody = b
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if err := r.record(req, reqBody, resp, respBody); err != nil {
+		return nil, fmt.Errorf("record interaction: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) record(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.cassette.Interactions) + 1
+
+	in := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: redactHeader(req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+		},
+	}
+	var err error
+	in.Request.Body, in.Request.BodyFile, err = r.storeBody(fmt.Sprintf("%04d-request", n), req.Header.Get("Content-Type"), reqBody)
+	if err != nil {
+		return err
+	}
+	in.Response.Body, in.Response.BodyFile, err = r.storeBody(fmt.Sprintf("%04d-response", n), resp.Header.Get("Content-Type"), respBody)
+	if err != nil {
+		return err
+	}
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	return r.save()
+}
+
+// storeBody returns body as an inline string if it is text, otherwise
+// writes it to the files directory and returns the relative path.
+func (r *Recorder) storeBody(name, contentType string, body []byte) (inline, file string, err error) {
+	if len(body) == 0 {
+		return "", "", nil
+	}
+	if isTextContent(contentType, body) {
+		return string(body), "", nil
+	}
+	dir := r.path + ".files"
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("create cassette files directory: %w", err)
+	}
+	name += bodyFileExt(contentType)
+	if err := os.WriteFile(filepath.Join(dir, name), body, 0o644); err != nil {
+		return "", "", fmt.Errorf("write body file: %w", err)
+	}
+	return "", filepath.ToSlash(filepath.Join(filepath.Base(dir), name)), nil
+}
+
+// save writes the cassette to the file atomically. r.mu must be held.
+func (r *Recorder) save() error {
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal cassette: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create cassette file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write cassette file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write cassette file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("write cassette file: %w", err)
+	}
+	return nil
+}
+
+// Replayer is an http.RoundTripper that serves responses from a cassette
+// file saved by Recorder, without accessing the network.
+//
+// A request is matched against the recorded ones by its method, path and
+// query, ignoring the host. Each recorded interaction is served only once,
+// in the recorded order.
+type Replayer struct {
+	dir      string
+	cassette Cassette
+
+	mu   sync.Mutex
+	used []bool
+}
+
+// LoadReplayer loads the cassette file at path.
+func LoadReplayer(path string) (*Replayer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette file: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse cassette file %q: %w", path, err)
+	}
+	return &Replayer{
+		dir:      filepath.Dir(path),
+		cassette: c,
+		used:     make([]bool, len(c.Interactions)),
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	in, err := r.next(req)
+	if err != nil {
+		return nil, err
+	}
+	body := []byte(in.Response.Body)
+	if in.Response.BodyFile != "" {
+		body, err = os.ReadFile(filepath.Join(r.dir, filepath.FromSlash(in.Response.BodyFile)))
+		if err != nil {
+			return nil, fmt.Errorf("read body file: %w", err)
+		}
+	}
+	header := in.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+		StatusCode:    in.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// next returns the first unused interaction that matches req.
+func (r *Replayer) next(req *http.Request) (*Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.cassette.Interactions {
+		in := &r.cassette.Interactions[i]
+		if r.used[i] || in.Request.Method != req.Method {
+			continue
+		}
+		u, err := url.Parse(in.Request.URL)
+		if err != nil || u.Path != req.URL.Path || !sameQuery(u.Query(), req.URL.Query()) {
+			continue
+		}
+		r.used[i] = true
+		return in, nil
+	}
+	return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL.RequestURI())
+}
+
+// sameQuery reports whether a and b have the same parameters, ignoring the order.
+func sameQuery(a, b url.Values) bool {
+	return a.Encode() == b.Encode()
+}
+
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range sensitiveHeaders {
+		if _, ok := h[name]; ok {
+			h.Set(name, redacted)
+		}
+	}
+	return h
+}
+
+// isTextContent reports whether a body of contentType can be stored inline.
+func isTextContent(contentType string, body []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/x-www-form-urlencoded":
+		return utf8.Valid(body)
+	}
+	return false
+}
+
+// bodyFileExt returns the file extension for contentType, or ".bin" if unknown.
+func bodyFileExt(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/pdf":
+		return ".pdf"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "multipart/form-data":
+		return ".multipart"
+	}
+	return ".bin"
+}
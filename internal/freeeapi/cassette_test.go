@@ -0,0 +1,101 @@
+package freeeapi
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n\x00\xff binary")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/1/receipts/1/download":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(pdf)
+		case "/api/1/receipts":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"receipt":{"id":1}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cassette.json")
+	client := &http.Client{Transport: NewRecorder(path, srv.Client().Transport)}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/1/receipts", bytes.NewReader([]byte("\x00upload")))
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	mustDo(t, client, req)
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/api/1/receipts/1/download?company_id=1", nil)
+	mustDo(t, client, req)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-token") {
+		t.Errorf("cassette contains the access token:\n%s", data)
+	}
+	for _, want := range []string{`"REDACTED"`, `"cassette.json.files/0001-request.multipart"`, `"cassette.json.files/0002-response.pdf"`, `{\"receipt\":{\"id\":1}}`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("cassette does not contain %s:\n%s", want, data)
+		}
+	}
+
+	replayer, err := LoadReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: replayer}
+
+	// The host is ignored.
+	req, _ = http.NewRequest(http.MethodGet, "http://replay.invalid/api/1/receipts/1/download?company_id=1", nil)
+	resp := mustDo(t, client, req)
+	if got := readAll(t, resp); !bytes.Equal(got, pdf) {
+		t.Errorf("replayed body = %q, want %q", got, pdf)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/pdf" {
+		t.Errorf("replayed Content-Type = %q, want application/pdf", got)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "http://replay.invalid/api/1/receipts", strings.NewReader("other body"))
+	resp = mustDo(t, client, req)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("replayed status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	// Each interaction is served only once.
+	req, _ = http.NewRequest(http.MethodPost, "http://replay.invalid/api/1/receipts", nil)
+	if _, err := client.Do(req); err == nil {
+		t.Error("replaying a consumed interaction: error = nil, want error")
+	}
+}
+
+func mustDo(t *testing.T, client *http.Client, req *http.Request) *http.Response {
+	t.Helper()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	return resp
+}
+
+func readAll(t *testing.T, resp *http.Response) []byte {
+	t.Helper()
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,59 @@
+package freeeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// NewDealUpdateParams returns the parameters to update the deal d as it is.
+//
+// The update API replaces the whole deal, so the details and the attached
+// receipts of d are carried over. Callers change only the fields they need.
+func NewDealUpdateParams(d *apigen.Deal) (*apigen.DealUpdateParams, error) {
+	// The details of Deal and DealUpdateParams are different anonymous structs
+	// sharing the JSON field names, so convert them through JSON.
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("marshal deal: %w", err)
+	}
+	var params apigen.DealUpdateParams
+	if err := json.Unmarshal(b, &params); err != nil {
+		return nil, fmt.Errorf("unmarshal deal update params: %w", err)
+	}
+	ids := DealReceiptIDs(d)
+	params.ReceiptIds = &ids
+	return &params, nil
+}
+
+// DealReceiptIDs returns the IDs of the receipts attached to the deal d.
+func DealReceiptIDs(d *apigen.Deal) []int64 {
+	ids := []int64{}
+	if d.Receipts == nil {
+		return ids
+	}
+	for _, r := range *d.Receipts {
+		ids = append(ids, r.Id)
+	}
+	return ids
+}
+
+// AddIDs returns ids with the elements of add appended, skipping those already included.
+func AddIDs(ids, add []int64) []int64 {
+	ids = slices.Clone(ids)
+	for _, id := range add {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// RemoveIDs returns ids without the elements of remove.
+func RemoveIDs(ids, remove []int64) []int64 {
+	return slices.DeleteFunc(slices.Clone(ids), func(id int64) bool {
+		return slices.Contains(remove, id)
+	})
+}
@@ -0,0 +1,185 @@
+package freeeapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// maxLoggedBody is the maximum number of bytes of a body written to the debug log.
+const maxLoggedBody = 4096
+
+// sensitiveParams are the query parameters, form fields and JSON keys whose
+// values are not written to the debug log.
+var sensitiveParams = []string{
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"client_secret",
+	"code",
+	"password",
+}
+
+// DebugRequestEditor returns a RequestEditorFn that logs each freee API request
+// before it is sent. Sensitive query parameters are redacted.
+func DebugRequestEditor(logger *slog.Logger) apigen.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		logger.DebugContext(ctx, "freee api request",
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.String("query", redactQuery(req.URL.Query()).Encode()),
+			slog.Int64("request_size", req.ContentLength),
+		)
+		return nil
+	}
+}
+
+// DebugTransport is an http.RoundTripper that logs every HTTP exchange,
+// including the OAuth2 token requests, with its status, latency and sizes.
+//
+// Tokens and the client secret are redacted from the logged URLs and bodies.
+// Headers are not logged.
+type DebugTransport struct {
+	// Base is the underlying transport. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Logger is the destination of the log.
+	Logger *slog.Logger
+	// Bodies enables logging of request and response bodies.
+	Bodies bool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx := req.Context()
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req.URL)),
+	}
+	reqSize := req.ContentLength
+	if t.Bodies && req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(ctx)
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		reqSize = int64(len(b))
+		attrs = append(attrs, slog.String("request_body", redactBody(req.Header.Get("Content-Type"), b)))
+	}
+	attrs = append(attrs, slog.Int64("request_size", reqSize))
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	attrs = append(attrs, slog.Duration("latency", time.Since(start)))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		t.Logger.LogAttrs(ctx, slog.LevelDebug, "http error", attrs...)
+		return nil, err
+	}
+
+	respSize := resp.ContentLength
+	if t.Bodies {
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(b))
+		respSize = int64(len(b))
+		attrs = append(attrs, slog.String("response_body", redactBody(resp.Header.Get("Content-Type"), b)))
+	}
+	attrs = append(attrs,
+		slog.Int("status", resp.StatusCode),
+		slog.Int64("response_size", respSize),
+	)
+	t.Logger.LogAttrs(ctx, slog.LevelDebug, "http response", attrs...)
+	return resp, nil
+}
+
+func isSensitiveParam(name string) bool {
+	return slices.Contains(sensitiveParams, name)
+}
+
+func redactQuery(q url.Values) url.Values {
+	for name := range q {
+		if isSensitiveParam(name) {
+			q[name] = []string{redacted}
+		}
+	}
+	return q
+}
+
+// redactURL returns u as a string with the sensitive query parameters and user info redacted.
+func redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	c.RawQuery = redactQuery(u.Query()).Encode()
+	return c.String()
+}
+
+// redactBody returns a loggable representation of body.
+// Sensitive values in form and JSON bodies are redacted, and binary bodies are omitted.
+func redactBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if !isTextContent(contentType, body) {
+		return "(binary)"
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		if q, err := url.ParseQuery(string(body)); err == nil {
+			body = []byte(redactQuery(q).Encode())
+		}
+	case "application/json":
+		var v any
+		if err := json.Unmarshal(body, &v); err == nil {
+			if b, err := json.Marshal(redactJSON(v)); err == nil {
+				body = b
+			}
+		}
+	}
+	if len(body) > maxLoggedBody {
+		n := maxLoggedBody
+		for n > 0 && !utf8.RuneStart(body[n]) {
+			n--
+		}
+		return string(body[:n]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+func redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if isSensitiveParam(k) {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redactJSON(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = redactJSON(e)
+		}
+	}
+	return v
+}
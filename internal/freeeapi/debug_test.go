@@ -0,0 +1,49 @@
+package freeeapi
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"access_token":"issued-token","refresh_token":"issued-refresh","token_type":"bearer"}`)
+	}))
+	defer srv.Close()
+
+	var log bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := &http.Client{Transport: &DebugTransport{Base: srv.Client().Transport, Logger: logger, Bodies: true}}
+
+	body := "grant_type=authorization_code&code=auth-code&client_id=my-client&client_secret=my-secret"
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/public_api/token?access_token=query-token", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer header-token")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(got), "issued-token") {
+		t.Errorf("response body was not passed through: %s", got)
+	}
+
+	out := log.String()
+	for _, secret := range []string{"auth-code", "my-secret", "query-token", "header-token", "issued-token", "issued-refresh"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("debug log contains %q:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{"method=POST", "status=200", "latency=", "request_size=", "response_size=", "client_id=my-client", "token_type"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("debug log does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package fake
+
+import (
+	"net/http"
+)
+
+func (s *Server) handleUsersMe(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	companies := make([]map[string]any, 0, len(s.companies))
+	for _, c := range s.companies {
+		companies = append(companies, map[string]any{
+			"id":              c.ID,
+			"display_name":    c.DisplayName,
+			"role":            c.Role,
+			"use_custom_role": false,
+			"advisor_id":      nil,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user": map[string]any{
+			"id":              s.user.ID,
+			"email":           s.user.Email,
+			"display_name":    s.user.DisplayName,
+			"first_name":      nil,
+			"last_name":       nil,
+			"first_name_kana": nil,
+			"last_name_kana":  nil,
+			"companies":       companies,
+		},
+	})
+}
+
+func (s *Server) handleGetCompanies(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	companies := make([]map[string]any, 0, len(s.companies))
+	for _, c := range s.companies {
+		companies = append(companies, map[string]any{
+			"id":             c.ID,
+			"name":           c.Name,
+			"name_kana":      c.NameKana,
+			"display_name":   c.DisplayName,
+			"company_number": c.CompanyNumber,
+			"role":           c.Role,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"companies": companies})
+}
+
+func (s *Server) handleGetCompany(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := parseInt64(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "事業所IDが不正です: %q", r.PathValue("id"))
+		return
+	}
+	c, ok := s.findCompany(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "事業所が見つかりません: %d", id)
+		return
+	}
+	fiscalYears := make([]map[string]any, 0, len(c.FiscalYears))
+	for _, fy := range c.FiscalYears {
+		fiscalYears = append(fiscalYears, map[string]any{
+			"start_date":                 fy[0],
+			"end_date":                   fy[1],
+			"depreciation_record_method": 0,
+			"indirect_write_off_method":  false,
+			"return_code":                0,
+			"sales_tax_business_code":    0,
+			"tax_account_method":         0,
+			"tax_fraction":               0,
+			"tax_method":                 0,
+			"use_industry_template":      false,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"company": map[string]any{
+			"id":                      c.ID,
+			"name":                    c.Name,
+			"name_kana":               c.NameKana,
+			"display_name":            c.DisplayName,
+			"company_number":          c.CompanyNumber,
+			"corporate_number":        c.CorporateNumber,
+			"role":                    c.Role,
+			"fiscal_years":            fiscalYears,
+			"amount_fraction":         0,
+			"minus_format":            0,
+			"org_code":                1,
+			"private_settlement":      false,
+			"tax_at_source_calc_type": 0,
+			"txn_number_format":       "not_used",
+			"use_partner_code":        false,
+			"invoice_layout":          "default_classic",
+			"workflow_setting":        "disabled",
+		},
+	})
+}
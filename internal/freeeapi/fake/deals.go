@@ -0,0 +1,289 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// deal is a stored deal with the IDs of its attached receipts.
+type deal struct {
+	companyID  int64
+	deal       apigen.Deal
+	receiptIDs []int64
+}
+
+// AddDeal stores a deal of the company with the given receipts attached, and returns its ID.
+// The ID, company ID and amount of the given deal are filled in by the server.
+func (s *Server) AddDeal(companyID int64, d apigen.Deal, receiptIDs ...int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	d.Id = s.nextID
+	d.CompanyId = companyID
+	d.Amount = dealAmount(d)
+	s.deals = append(s.deals, &deal{companyID: companyID, deal: d, receiptIDs: slices.Clone(receiptIDs)})
+	return d.Id
+}
+
+// Deal returns the stored deal with its attached receipts.
+func (s *Server) Deal(id int64) (apigen.Deal, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.deals {
+		if d.deal.Id == id {
+			return s.renderDeal(d), true
+		}
+	}
+	return apigen.Deal{}, false
+}
+
+// renderDeal returns the deal with the receipts filled in. s.mu must be held.
+func (s *Server) renderDeal(d *deal) apigen.Deal {
+	rendered := d.deal
+	receipts := []apigen.Receipt{}
+	for _, id := range d.receiptIDs {
+		if r, ok := s.findReceipt(d.companyID, id); ok {
+			receipts = append(receipts, r.receipt)
+		}
+	}
+	// Deal.Receipts is an anonymous struct sharing the JSON field names with Receipt.
+	b, _ := json.Marshal(receipts)
+	json.Unmarshal(b, &rendered.Receipts)
+	return rendered
+}
+
+// hasDeal reports whether the receipt is attached to any deal. s.mu must be held.
+func (s *Server) hasDeal(receiptID int64) bool {
+	for _, d := range s.deals {
+		if slices.Contains(d.receiptIDs, receiptID) {
+			return true
+		}
+	}
+	return false
+}
+
+func dealAmount(d apigen.Deal) int64 {
+	var amount int64
+	if d.Details != nil {
+		for _, detail := range *d.Details {
+			amount += detail.Amount
+		}
+	}
+	return amount
+}
+
+func (s *Server) handleGetDeal(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	companyID, ok := s.companyIDParam(w, r.URL.Query().Get("company_id"))
+	if !ok {
+		return
+	}
+	d, ok := s.dealParam(w, r, companyID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, apigen.DealResponse{Deal: s.renderDeal(d)})
+}
+
+func (s *Server) handleUpdateDeal(w http.ResponseWriter, r *http.Request) {
+	var params apigen.DealUpdateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "リクエストボディが不正です: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.findCompany(params.CompanyId); !ok {
+		writeError(w, http.StatusNotFound, "事業所が見つかりません: %d", params.CompanyId)
+		return
+	}
+	d, ok := s.dealParam(w, r, params.CompanyId)
+	if !ok {
+		return
+	}
+	if len(params.Details) == 0 {
+		writeError(w, http.StatusBadRequest, "details を指定してください")
+		return
+	}
+	if params.IssueDate == "" {
+		writeError(w, http.StatusBadRequest, "issue_date を指定してください")
+		return
+	}
+	if params.ReceiptIds != nil {
+		for _, id := range *params.ReceiptIds {
+			if rc, ok := s.findReceipt(params.CompanyId, id); !ok || rc.receipt.Status == apigen.ReceiptStatusDeleted {
+				writeError(w, http.StatusBadRequest, "証憑が見つかりません: %d", id)
+				return
+			}
+		}
+		d.receiptIDs = slices.Clone(*params.ReceiptIds)
+	}
+
+	// The details of DealUpdateParams and Deal share the JSON field names.
+	b, _ := json.Marshal(params.Details)
+	d.deal.Details = nil
+	json.Unmarshal(b, &d.deal.Details)
+	for i := range *d.deal.Details {
+		detail := &(*d.deal.Details)[i]
+		if detail.Id == 0 {
+			s.nextID++
+			detail.Id = s.nextID
+		}
+		if params.Type == apigen.DealUpdateParamsTypeIncome {
+			detail.EntrySide = "credit"
+		} else {
+			detail.EntrySide = "debit"
+		}
+	}
+	d.deal.IssueDate = params.IssueDate
+	d.deal.DueDate = params.DueDate
+	d.deal.PartnerId = params.PartnerId
+	d.deal.PartnerCode = params.PartnerCode
+	d.deal.RefNumber = params.RefNumber
+	d.deal.Type = ptr(apigen.DealType(params.Type))
+	d.deal.Amount = dealAmount(d.deal)
+	writeJSON(w, http.StatusOK, apigen.DealResponse{Deal: s.renderDeal(d)})
+}
+
+// dealParam returns the deal specified by the {id} path parameter.
+// It writes an error response if not found. s.mu must be held.
+func (s *Server) dealParam(w http.ResponseWriter, r *http.Request, companyID int64) (*deal, bool) {
+	id, ok := parseInt64(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "取引IDが不正です: %q", r.PathValue("id"))
+		return nil, false
+	}
+	for _, d := range s.deals {
+		if d.companyID == companyID && d.deal.Id == id {
+			return d, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "取引が見つかりません: %d", id)
+	return nil, false
+}
+
+func (s *Server) handleCreateDeal(w http.ResponseWriter, r *http.Request) {
+	var params apigen.DealCreateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "リクエストボディが不正です: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.findCompany(params.CompanyId); !ok {
+		writeError(w, http.StatusNotFound, "事業所が見つかりません: %d", params.CompanyId)
+		return
+	}
+	if len(params.Details) == 0 {
+		writeError(w, http.StatusBadRequest, "details を指定してください")
+		return
+	}
+	if params.IssueDate == "" {
+		writeError(w, http.StatusBadRequest, "issue_date を指定してください")
+		return
+	}
+	for _, detail := range params.Details {
+		if detail.AccountItemId == nil && detail.AccountItemCode == nil {
+			writeError(w, http.StatusBadRequest, "勘定科目を指定してください")
+			return
+		}
+	}
+	var receiptIDs []int64
+	if params.ReceiptIds != nil {
+		for _, id := range *params.ReceiptIds {
+			if rc, ok := s.findReceipt(params.CompanyId, id); !ok || rc.receipt.Status == apigen.ReceiptStatusDeleted {
+				writeError(w, http.StatusBadRequest, "証憑が見つかりません: %d", id)
+				return
+			}
+		}
+		receiptIDs = *params.ReceiptIds
+	}
+
+	// The details of DealCreateParams and Deal share the JSON field names.
+	var d apigen.Deal
+	b, _ := json.Marshal(params.Details)
+	json.Unmarshal(b, &d.Details)
+	for i := range *d.Details {
+		s.nextID++
+		(*d.Details)[i].Id = s.nextID
+		if params.Type == apigen.DealCreateParamsTypeIncome {
+			(*d.Details)[i].EntrySide = "credit"
+		} else {
+			(*d.Details)[i].EntrySide = "debit"
+		}
+	}
+	s.nextID++
+	d.Id = s.nextID
+	d.CompanyId = params.CompanyId
+	d.IssueDate = params.IssueDate
+	d.DueDate = params.DueDate
+	d.PartnerId = params.PartnerId
+	d.PartnerCode = params.PartnerCode
+	d.RefNumber = params.RefNumber
+	d.Type = ptr(apigen.DealType(params.Type))
+	d.Amount = dealAmount(d)
+	d.Status = apigen.DealStatusUnsettled
+	if params.Payments != nil {
+		// The payments of DealCreateParams and Deal share the JSON field names.
+		b, _ := json.Marshal(params.Payments)
+		json.Unmarshal(b, &d.Payments)
+		var paid int64
+		for i := range *d.Payments {
+			s.nextID++
+			(*d.Payments)[i].Id = s.nextID
+			paid += (*d.Payments)[i].Amount
+		}
+		if paid >= d.Amount {
+			d.Status = apigen.DealStatusSettled
+		}
+	}
+	created := &deal{companyID: params.CompanyId, deal: d, receiptIDs: slices.Clone(receiptIDs)}
+	s.deals = append(s.deals, created)
+	writeJSON(w, http.StatusCreated, apigen.DealResponse{Deal: s.renderDeal(created)})
+}
+
+func (s *Server) handleGetDeals(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	companyID, ok := s.companyIDParam(w, q.Get("company_id"))
+	if !ok {
+		return
+	}
+	limit, offset := int64(20), int64(0)
+	if v := q.Get("limit"); v != "" {
+		if limit, ok = parseInt64(v); !ok || limit < 1 || limit > 100 {
+			writeError(w, http.StatusBadRequest, "limit が不正です: %q", v)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, ok = parseInt64(v); !ok || offset < 0 {
+			writeError(w, http.StatusBadRequest, "offset が不正です: %q", v)
+			return
+		}
+	}
+	// Issue dates are yyyy-mm-dd, so they can be compared as strings.
+	start, end := q.Get("start_issue_date"), q.Get("end_issue_date")
+	deals := []apigen.Deal{}
+	for _, d := range s.deals {
+		if d.companyID != companyID {
+			continue
+		}
+		if (start != "" && d.deal.IssueDate < start) || (end != "" && d.deal.IssueDate > end) {
+			continue
+		}
+		deals = append(deals, s.renderDeal(d))
+	}
+	total := len(deals)
+	deals = deals[min(offset, int64(len(deals))):]
+	deals = deals[:min(limit, int64(len(deals)))]
+	writeJSON(w, http.StatusOK, map[string]any{
+		"deals": deals,
+		"meta":  map[string]any{"total_count": total},
+	})
+}
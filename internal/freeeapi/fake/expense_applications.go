@@ -0,0 +1,149 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// ExpenseApplication returns the stored expense application.
+func (s *Server) ExpenseApplication(id int64) (apigen.ExpenseApplicationResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.expenseApplications {
+		if a.ExpenseApplication.Id == id {
+			return *a, true
+		}
+	}
+	return apigen.ExpenseApplicationResponse{}, false
+}
+
+func (s *Server) handleCreateExpenseApplication(w http.ResponseWriter, r *http.Request) {
+	var params apigen.ExpenseApplicationCreateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "リクエストボディが不正です: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.findCompany(params.CompanyId); !ok {
+		writeError(w, http.StatusNotFound, "事業所が見つかりません: %d", params.CompanyId)
+		return
+	}
+	if params.Title == "" {
+		writeError(w, http.StatusBadRequest, "title を指定してください")
+		return
+	}
+	if params.PurchaseLines != nil {
+		for _, line := range *params.PurchaseLines {
+			if line.TransactionDate == "" {
+				writeError(w, http.StatusBadRequest, "transaction_date を指定してください")
+				return
+			}
+			ids := []int64{}
+			if line.ReceiptId != nil {
+				ids = append(ids, *line.ReceiptId)
+			}
+			if line.SubReceiptIds != nil {
+				if len(*line.SubReceiptIds) > 5 {
+					writeError(w, http.StatusBadRequest, "sub_receipt_ids は5個まで指定できます")
+					return
+				}
+				ids = append(ids, *line.SubReceiptIds...)
+			}
+			for _, id := range ids {
+				if rc, ok := s.findReceipt(params.CompanyId, id); !ok || rc.receipt.Status == apigen.ReceiptStatusDeleted {
+					writeError(w, http.StatusBadRequest, "証憑が見つかりません: %d", id)
+					return
+				}
+			}
+		}
+	}
+
+	a := &apigen.ExpenseApplicationResponse{}
+	e := &a.ExpenseApplication
+	// The purchase lines of the params and the response share the JSON field names.
+	b, _ := json.Marshal(params.PurchaseLines)
+	json.Unmarshal(b, &e.PurchaseLines)
+	var total int64
+	if e.PurchaseLines != nil {
+		for i := range *e.PurchaseLines {
+			line := &(*e.PurchaseLines)[i]
+			s.nextID++
+			line.Id = s.nextID
+			if line.ExpenseApplicationLines == nil {
+				continue
+			}
+			for j := range *line.ExpenseApplicationLines {
+				l := &(*line.ExpenseApplicationLines)[j]
+				s.nextID++
+				l.Id = s.nextID
+				total += deref(l.Amount)
+			}
+		}
+	}
+	s.nextID++
+	e.Id = s.nextID
+	e.CompanyId = params.CompanyId
+	e.ApplicantId = s.user.ID
+	e.ApplicationNumber = fmt.Sprint(len(s.expenseApplications) + 1)
+	e.Title = params.Title
+	e.Description = params.Description
+	e.IssueDate = time.Now().In(jst).Format(time.DateOnly)
+	if params.IssueDate != nil {
+		e.IssueDate = *params.IssueDate
+	}
+	e.ApprovalFlowRouteId = deref(params.ApprovalFlowRouteId)
+	e.SectionId = params.SectionId
+	e.TagIds = params.TagIds
+	e.Status = apigen.ExpenseApplicationResponseExpenseApplicationStatusDraft
+	if params.Draft != nil && !*params.Draft {
+		e.Status = apigen.ExpenseApplicationResponseExpenseApplicationStatusInProgress
+	}
+	e.TotalAmount = &total
+	s.expenseApplications = append(s.expenseApplications, a)
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (s *Server) handleGetExpenseApplications(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	companyID, ok := s.companyIDParam(w, q.Get("company_id"))
+	if !ok {
+		return
+	}
+	limit, offset := int64(50), int64(0)
+	if v := q.Get("limit"); v != "" {
+		if limit, ok = parseInt64(v); !ok || limit < 1 || limit > 500 {
+			writeError(w, http.StatusBadRequest, "limit が不正です: %q", v)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, ok = parseInt64(v); !ok || offset < 0 {
+			writeError(w, http.StatusBadRequest, "offset が不正です: %q", v)
+			return
+		}
+	}
+	// Issue dates are yyyy-mm-dd, so they can be compared as strings.
+	start, end := q.Get("start_issue_date"), q.Get("end_issue_date")
+	// The elements of the index response are anonymous structs sharing the JSON field names.
+	applications := []any{}
+	for _, a := range s.expenseApplications {
+		e := a.ExpenseApplication
+		if e.CompanyId != companyID {
+			continue
+		}
+		if (start != "" && e.IssueDate < start) || (end != "" && e.IssueDate > end) {
+			continue
+		}
+		applications = append(applications, e)
+	}
+	applications = applications[min(offset, int64(len(applications))):]
+	applications = applications[:min(limit, int64(len(applications)))]
+	writeJSON(w, http.StatusOK, map[string]any{"expense_applications": applications})
+}
@@ -0,0 +1,285 @@
+// Package fake provides an in-memory fake of the freee API for offline use.
+//
+// The fake implements the subset of the freee accounting API used by ffbox:
+// companies, users/me, receipts (filebox), deals, manual journals, expense
+// applications, payment requests, wallet transactions and partners. It is meant
+// to be served with net/http or net/http/httptest, so that scripts and
+// integration tests can run against it without network access:
+//
+//	srv := fake.New()
+//	srv.SeedSampleData()
+//	ts := httptest.NewServer(srv)
+//	defer ts.Close()
+//
+// Requests must carry an "Authorization: Bearer <token>" header. If Token is
+// set, the bearer token must match it; otherwise any token is accepted.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// Server is an in-memory fake of the freee API. It implements [http.Handler].
+type Server struct {
+	// Token is the bearer token that requests must carry.
+	// If empty, any bearer token is accepted.
+	Token string
+
+	mu        sync.Mutex
+	mux       *http.ServeMux
+	nextID    int64
+	user      User
+	companies []Company
+	receipts  []*receipt
+	deals     []*deal
+	partners  []Partner
+
+	manualJournals      []*apigen.ManualJournal
+	expenseApplications []*apigen.ExpenseApplicationResponse
+	paymentRequests     []*apigen.PaymentRequestResponse
+	walletTxns          []apigen.WalletTxn
+}
+
+// User is the user that is returned from /api/1/users/me and
+// recorded as the uploader of receipts.
+type User struct {
+	ID          int64
+	Email       string
+	DisplayName string
+}
+
+// Company is a company that the user belongs to.
+type Company struct {
+	ID              int64
+	Name            string
+	DisplayName     string
+	NameKana        string
+	CompanyNumber   string
+	CorporateNumber string
+	Role            string
+	// FiscalYears holds pairs of start and end dates (yyyy-mm-dd)
+	FiscalYears [][2]string
+}
+
+// receipt is a stored receipt with its file content.
+type receipt struct {
+	companyID int64
+	receipt   apigen.Receipt
+	filename  string
+	content   []byte
+}
+
+// New creates an empty fake server with a default user.
+func New() *Server {
+	s := &Server{
+		nextID: 1000,
+		user:   User{ID: 1, Email: "user@example.com", DisplayName: "freee 太郎"},
+	}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("GET /api/1/users/me", s.handleUsersMe)
+	s.mux.HandleFunc("GET /api/1/companies", s.handleGetCompanies)
+	s.mux.HandleFunc("GET /api/1/companies/{id}", s.handleGetCompany)
+	s.mux.HandleFunc("GET /api/1/receipts", s.handleGetReceipts)
+	s.mux.HandleFunc("POST /api/1/receipts", s.handleCreateReceipt)
+	s.mux.HandleFunc("GET /api/1/receipts/{id}", s.handleGetReceipt)
+	s.mux.HandleFunc("PUT /api/1/receipts/{id}", s.handleUpdateReceipt)
+	s.mux.HandleFunc("DELETE /api/1/receipts/{id}", s.handleDestroyReceipt)
+	s.mux.HandleFunc("GET /api/1/receipts/{id}/download", s.handleDownloadReceipt)
+	s.mux.HandleFunc("GET /api/1/deals", s.handleGetDeals)
+	s.mux.HandleFunc("POST /api/1/deals", s.handleCreateDeal)
+	s.mux.HandleFunc("GET /api/1/deals/{id}", s.handleGetDeal)
+	s.mux.HandleFunc("PUT /api/1/deals/{id}", s.handleUpdateDeal)
+	s.mux.HandleFunc("GET /api/1/partners", s.handleGetPartners)
+	s.mux.HandleFunc("GET /api/1/manual_journals", s.handleGetManualJournals)
+	s.mux.HandleFunc("GET /api/1/manual_journals/{id}", s.handleGetManualJournal)
+	s.mux.HandleFunc("PUT /api/1/manual_journals/{id}", s.handleUpdateManualJournal)
+	s.mux.HandleFunc("GET /api/1/expense_applications", s.handleGetExpenseApplications)
+	s.mux.HandleFunc("POST /api/1/expense_applications", s.handleCreateExpenseApplication)
+	s.mux.HandleFunc("POST /api/1/payment_requests", s.handleCreatePaymentRequest)
+	s.mux.HandleFunc("GET /api/1/wallet_txns", s.handleGetWalletTxns)
+	return s
+}
+
+// ServeHTTP implements [http.Handler].
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(r)
+	if !ok || (s.Token != "" && token != s.Token) {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{
+			"message": "アクセストークンが不正です",
+		})
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// SetUser replaces the current user.
+func (s *Server) SetUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = u
+}
+
+// AddCompany adds a company that the user belongs to.
+func (s *Server) AddCompany(c Company) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.Role == "" {
+		c.Role = "admin"
+	}
+	s.companies = append(s.companies, c)
+}
+
+// SeedSampleData adds a company and a few receipts for trying ffbox out.
+func (s *Server) SeedSampleData() {
+	// Data is dated relative to now, so that it appears in the default range of ffbox list.
+	now := time.Now().In(jst)
+	fy := now.Format("2006")
+	s.AddCompany(Company{
+		ID:            1,
+		Name:          "サンプル株式会社",
+		DisplayName:   "サンプル株式会社",
+		NameKana:      "サンプルカブシキガイシャ",
+		CompanyNumber: "1234567890",
+		Role:          "admin",
+		FiscalYears:   [][2]string{{fy + "-01-01", fy + "-12-31"}},
+	})
+	samples := []struct {
+		partner string
+		daysAgo int
+		amount  int64
+	}{
+		{"株式会社サンプル商事", 14, 3300},
+		{"Example Cloud Inc.", 7, 12000},
+		{"サンプル文具店", 2, 880},
+	}
+	for _, r := range samples {
+		createdAt := now.AddDate(0, 0, -r.daysAgo)
+		issueDate := createdAt.AddDate(0, 0, -1).Format(time.DateOnly)
+		s.AddReceipt(1, apigen.Receipt{
+			CreatedAt:        createdAt.Format(time.RFC3339),
+			Status:           apigen.ReceiptStatusConfirmed,
+			Origin:           apigen.PublicApi,
+			MimeType:         "application/pdf",
+			DocumentType:     ptr(apigen.ReceiptDocumentType("receipt")),
+			QualifiedInvoice: ptr(apigen.ReceiptQualifiedInvoice("unselected")),
+			ReceiptMetadatum: newMetadatum(&r.amount, &issueDate, &r.partner),
+		}, "receipt.pdf", []byte("%PDF-1.4\n"))
+	}
+}
+
+// AddReceipt stores a receipt of the company and returns its ID.
+// The ID and the uploader of the given receipt are filled in by the server.
+func (s *Server) AddReceipt(companyID int64, r apigen.Receipt, filename string, content []byte) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	r.Id = s.nextID
+	r.User.Id = s.user.ID
+	r.User.Email = s.user.Email
+	r.User.DisplayName = ptr(s.user.DisplayName)
+	s.receipts = append(s.receipts, &receipt{companyID: companyID, receipt: r, filename: filename, content: content})
+	return r.Id
+}
+
+// Receipt returns the stored receipt and its file content.
+func (s *Server) Receipt(id int64) (apigen.Receipt, []byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.receipts {
+		if r.receipt.Id == id {
+			return r.receipt, r.content, true
+		}
+	}
+	return apigen.Receipt{}, nil, false
+}
+
+// findCompany returns the company with the given ID. s.mu must be held.
+func (s *Server) findCompany(id int64) (*Company, bool) {
+	for i := range s.companies {
+		if s.companies[i].ID == id {
+			return &s.companies[i], true
+		}
+	}
+	return nil, false
+}
+
+// findReceipt returns the receipt with the given ID of the company. s.mu must be held.
+func (s *Server) findReceipt(companyID, id int64) (*receipt, bool) {
+	for _, r := range s.receipts {
+		if r.companyID == companyID && r.receipt.Id == id {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// -----------------------------------------------------------------------------
+// Helpers
+// -----------------------------------------------------------------------------
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// deref returns the value p points to, or the zero value if p is nil.
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if len(h) <= len(prefix) || h[:len(prefix)] != prefix {
+		return "", false
+	}
+	return h[len(prefix):], true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response in the format of the freee API.
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]any{
+		"status_code": status,
+		"errors": []map[string]any{{
+			"type":     "status",
+			"messages": []string{fmt.Sprintf(format, args...)},
+		}},
+	})
+}
+
+// parseInt64 parses s as a decimal int64.
+func parseInt64(s string) (int64, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// companyIDParam returns the company_id parameter of the request and writes
+// an error response if it is missing or unknown. s.mu must be held.
+func (s *Server) companyIDParam(w http.ResponseWriter, raw string) (int64, bool) {
+	id, ok := parseInt64(raw)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "company_id が不正です: %q", raw)
+		return 0, false
+	}
+	if _, ok := s.findCompany(id); !ok {
+		writeError(w, http.StatusNotFound, "事業所が見つかりません: %d", id)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,154 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// AddManualJournal stores a manual journal of the company with the given receipts attached,
+// and returns its ID. The ID and company ID of the given journal are filled in by the server.
+func (s *Server) AddManualJournal(companyID int64, j apigen.ManualJournal, receiptIDs ...int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	j.Id = s.nextID
+	j.CompanyId = companyID
+	j.ReceiptIds = ptr(slices.Clone(receiptIDs))
+	s.manualJournals = append(s.manualJournals, &j)
+	return j.Id
+}
+
+// ManualJournal returns the stored manual journal.
+func (s *Server) ManualJournal(id int64) (apigen.ManualJournal, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.manualJournals {
+		if j.Id == id {
+			return *j, true
+		}
+	}
+	return apigen.ManualJournal{}, false
+}
+
+func (s *Server) handleGetManualJournals(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	companyID, ok := s.companyIDParam(w, q.Get("company_id"))
+	if !ok {
+		return
+	}
+	limit, offset := int64(20), int64(0)
+	if v := q.Get("limit"); v != "" {
+		if limit, ok = parseInt64(v); !ok || limit < 1 || limit > 500 {
+			writeError(w, http.StatusBadRequest, "limit が不正です: %q", v)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, ok = parseInt64(v); !ok || offset < 0 {
+			writeError(w, http.StatusBadRequest, "offset が不正です: %q", v)
+			return
+		}
+	}
+	// Issue dates are yyyy-mm-dd, so they can be compared as strings.
+	start, end := q.Get("start_issue_date"), q.Get("end_issue_date")
+	journals := []apigen.ManualJournal{}
+	for _, j := range s.manualJournals {
+		if j.CompanyId != companyID {
+			continue
+		}
+		if (start != "" && j.IssueDate < start) || (end != "" && j.IssueDate > end) {
+			continue
+		}
+		journals = append(journals, *j)
+	}
+	journals = journals[min(offset, int64(len(journals))):]
+	journals = journals[:min(limit, int64(len(journals)))]
+	writeJSON(w, http.StatusOK, map[string]any{"manual_journals": journals})
+}
+
+func (s *Server) handleGetManualJournal(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	companyID, ok := s.companyIDParam(w, r.URL.Query().Get("company_id"))
+	if !ok {
+		return
+	}
+	j, ok := s.manualJournalParam(w, r, companyID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, apigen.ManualJournalResponse{ManualJournal: *j})
+}
+
+func (s *Server) handleUpdateManualJournal(w http.ResponseWriter, r *http.Request) {
+	var params apigen.ManualJournalUpdateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "リクエストボディが不正です: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.findCompany(params.CompanyId); !ok {
+		writeError(w, http.StatusNotFound, "事業所が見つかりません: %d", params.CompanyId)
+		return
+	}
+	j, ok := s.manualJournalParam(w, r, params.CompanyId)
+	if !ok {
+		return
+	}
+	if len(params.Details) < 2 {
+		writeError(w, http.StatusBadRequest, "details は貸借それぞれ1行以上指定してください")
+		return
+	}
+	if params.IssueDate == "" {
+		writeError(w, http.StatusBadRequest, "issue_date を指定してください")
+		return
+	}
+	if params.ReceiptIds != nil {
+		for _, id := range *params.ReceiptIds {
+			if rc, ok := s.findReceipt(params.CompanyId, id); !ok || rc.receipt.Status == apigen.ReceiptStatusDeleted {
+				writeError(w, http.StatusBadRequest, "証憑が見つかりません: %d", id)
+				return
+			}
+		}
+		j.ReceiptIds = ptr(slices.Clone(*params.ReceiptIds))
+	}
+
+	// The details of ManualJournalUpdateParams and ManualJournal share the JSON field names.
+	b, _ := json.Marshal(params.Details)
+	j.Details = nil
+	json.Unmarshal(b, &j.Details)
+	for i := range j.Details {
+		if j.Details[i].Id == 0 {
+			s.nextID++
+			j.Details[i].Id = s.nextID
+		}
+	}
+	j.IssueDate = params.IssueDate
+	if params.Adjustment != nil {
+		j.Adjustment = *params.Adjustment
+	}
+	writeJSON(w, http.StatusOK, apigen.ManualJournalResponse{ManualJournal: *j})
+}
+
+// manualJournalParam returns the manual journal specified by the {id} path parameter.
+// It writes an error response if not found. s.mu must be held.
+func (s *Server) manualJournalParam(w http.ResponseWriter, r *http.Request, companyID int64) (*apigen.ManualJournal, bool) {
+	id, ok := parseInt64(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "振替伝票IDが不正です: %q", r.PathValue("id"))
+		return nil, false
+	}
+	for _, j := range s.manualJournals {
+		if j.CompanyId == companyID && j.Id == id {
+			return j, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "振替伝票が見つかりません: %d", id)
+	return nil, false
+}
@@ -0,0 +1,73 @@
+package fake
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Partner is a business partner of a company.
+type Partner struct {
+	ID        int64
+	CompanyID int64
+	Name      string
+	LongName  string
+	Code      string
+}
+
+// AddPartner adds a partner of the company.
+func (s *Server) AddPartner(p Partner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.partners = append(s.partners, p)
+}
+
+func (s *Server) handleGetPartners(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	companyID, ok := s.companyIDParam(w, q.Get("company_id"))
+	if !ok {
+		return
+	}
+	// The keyword matches the name, long name and code partially, like the real API.
+	keyword := q.Get("keyword")
+	partners := []map[string]any{}
+	for _, p := range s.partners {
+		if p.CompanyID != companyID {
+			continue
+		}
+		if keyword != "" && !strings.Contains(p.Name, keyword) && !strings.Contains(p.LongName, keyword) && !strings.Contains(p.Code, keyword) {
+			continue
+		}
+		partners = append(partners, map[string]any{
+			"id":                          p.ID,
+			"company_id":                  p.CompanyID,
+			"name":                        p.Name,
+			"long_name":                   nullIfEmpty(p.LongName),
+			"code":                        nullIfEmpty(p.Code),
+			"available":                   true,
+			"update_date":                 "2025-01-01",
+			"shortcut1":                   nil,
+			"shortcut2":                   nil,
+			"org_code":                    nil,
+			"country_code":                "JP",
+			"contact_name":                nil,
+			"default_title":               nil,
+			"email":                       nil,
+			"name_kana":                   nil,
+			"phone":                       nil,
+			"payer_walletable_id":         nil,
+			"transfer_fee_handling_side":  "payer",
+			"invoice_registration_number": nil,
+			"qualified_invoice_issuer":    false,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"partners": partners})
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,114 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// PaymentRequest returns the stored payment request.
+func (s *Server) PaymentRequest(id int64) (apigen.PaymentRequestResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.paymentRequests {
+		if p.PaymentRequest.Id == id {
+			return *p, true
+		}
+	}
+	return apigen.PaymentRequestResponse{}, false
+}
+
+func (s *Server) handleCreatePaymentRequest(w http.ResponseWriter, r *http.Request) {
+	var params apigen.PaymentRequestCreateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "リクエストボディが不正です: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.findCompany(params.CompanyId); !ok {
+		writeError(w, http.StatusNotFound, "事業所が見つかりません: %d", params.CompanyId)
+		return
+	}
+	switch {
+	case params.Title == "":
+		writeError(w, http.StatusBadRequest, "title を指定してください")
+		return
+	case params.IssueDate == "":
+		writeError(w, http.StatusBadRequest, "issue_date を指定してください")
+		return
+	case params.ApprovalFlowRouteId == 0:
+		writeError(w, http.StatusBadRequest, "approval_flow_route_id を指定してください")
+		return
+	case len(params.PaymentRequestLines) == 0:
+		writeError(w, http.StatusBadRequest, "payment_request_lines を指定してください")
+		return
+	}
+	var receiptIDs []int64
+	if params.ReceiptIds != nil {
+		for _, id := range *params.ReceiptIds {
+			if rc, ok := s.findReceipt(params.CompanyId, id); !ok || rc.receipt.Status == apigen.ReceiptStatusDeleted {
+				writeError(w, http.StatusBadRequest, "証憑が見つかりません: %d", id)
+				return
+			}
+		}
+		receiptIDs = slices.Clone(*params.ReceiptIds)
+	}
+
+	p := &apigen.PaymentRequestResponse{}
+	pr := &p.PaymentRequest
+	// The lines of the params and the response share the JSON field names.
+	b, _ := json.Marshal(params.PaymentRequestLines)
+	json.Unmarshal(b, &pr.PaymentRequestLines)
+	for i := range pr.PaymentRequestLines {
+		line := &pr.PaymentRequestLines[i]
+		s.nextID++
+		line.Id = s.nextID
+		if line.LineType == "" {
+			line.LineType = apigen.PaymentRequestResponsePaymentRequestPaymentRequestLinesLineTypeDealLine
+		}
+		if line.LineType == apigen.PaymentRequestResponsePaymentRequestPaymentRequestLinesLineTypeDealLine {
+			pr.TotalAmount += line.Amount
+		} else {
+			pr.TotalAmount -= line.Amount
+		}
+	}
+	s.nextID++
+	pr.Id = s.nextID
+	pr.CompanyId = params.CompanyId
+	pr.ApplicantId = s.user.ID
+	pr.ApplicationNumber = fmt.Sprint(len(s.paymentRequests) + 1)
+	pr.ApplicationDate = time.Now().In(jst).Format(time.DateOnly)
+	if params.ApplicationDate != nil {
+		pr.ApplicationDate = *params.ApplicationDate
+	}
+	pr.ApprovalFlowRouteId = params.ApprovalFlowRouteId
+	pr.Title = params.Title
+	pr.IssueDate = params.IssueDate
+	pr.Description = deref(params.Description)
+	pr.DocumentCode = deref(params.DocumentCode)
+	pr.PartnerId = params.PartnerId
+	pr.PartnerCode = params.PartnerCode
+	pr.PaymentDate = params.PaymentDate
+	pr.PaymentMethod = apigen.PaymentRequestResponsePaymentRequestPaymentMethodNone
+	if params.PaymentMethod != nil {
+		pr.PaymentMethod = apigen.PaymentRequestResponsePaymentRequestPaymentMethod(*params.PaymentMethod)
+	}
+	if params.QualifiedInvoiceStatus != nil {
+		pr.QualifiedInvoiceStatus = ptr(apigen.PaymentRequestResponsePaymentRequestQualifiedInvoiceStatus(*params.QualifiedInvoiceStatus))
+	}
+	pr.ReceiptIds = receiptIDs
+	if pr.ReceiptIds == nil {
+		pr.ReceiptIds = []int64{}
+	}
+	pr.Status = apigen.PaymentRequestResponsePaymentRequestStatusDraft
+	if !params.Draft {
+		pr.Status = apigen.PaymentRequestResponsePaymentRequestStatusInProgress
+	}
+	s.paymentRequests = append(s.paymentRequests, p)
+	writeJSON(w, http.StatusCreated, p)
+}
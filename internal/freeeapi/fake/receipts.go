@@ -0,0 +1,264 @@
+package fake
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// metadatum is the type of [apigen.Receipt] ReceiptMetadatum.
+type metadatum = struct {
+	Amount      *int64  `json:"amount"`
+	IssueDate   *string `json:"issue_date"`
+	PartnerName *string `json:"partner_name"`
+}
+
+func newMetadatum(amount *int64, issueDate, partnerName *string) *metadatum {
+	return &metadatum{Amount: amount, IssueDate: issueDate, PartnerName: partnerName}
+}
+
+func (s *Server) handleGetReceipts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	companyID, ok := s.companyIDParam(w, q.Get("company_id"))
+	if !ok {
+		return
+	}
+	startDate, err := time.Parse(time.DateOnly, q.Get("start_date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "start_date が不正です: %q", q.Get("start_date"))
+		return
+	}
+	endDate, err := time.Parse(time.DateOnly, q.Get("end_date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "end_date が不正です: %q", q.Get("end_date"))
+		return
+	}
+	limit, offset := int64(50), int64(0)
+	if v := q.Get("limit"); v != "" {
+		if limit, ok = parseInt64(v); !ok || limit < 1 || limit > 3000 {
+			writeError(w, http.StatusBadRequest, "limit が不正です: %q", v)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, ok = parseInt64(v); !ok || offset < 0 {
+			writeError(w, http.StatusBadRequest, "offset が不正です: %q", v)
+			return
+		}
+	}
+	category := q.Get("category")
+
+	receipts := []apigen.Receipt{}
+	for _, rc := range s.receipts {
+		if rc.companyID != companyID || !s.matchCategory(rc.receipt, category) {
+			continue
+		}
+		createdAt, err := time.Parse(time.RFC3339, rc.receipt.CreatedAt)
+		if err != nil {
+			continue
+		}
+		// start_date and end_date are inclusive dates in JST.
+		day := createdAt.In(jst).Format(time.DateOnly)
+		if day < startDate.Format(time.DateOnly) || endDate.Format(time.DateOnly) < day {
+			continue
+		}
+		receipts = append(receipts, rc.receipt)
+	}
+	slices.SortStableFunc(receipts, func(a, b apigen.Receipt) int {
+		return strings.Compare(a.CreatedAt, b.CreatedAt)
+	})
+	receipts = receipts[min(offset, int64(len(receipts))):]
+	receipts = receipts[:min(limit, int64(len(receipts)))]
+	writeJSON(w, http.StatusOK, map[string]any{"receipts": receipts})
+}
+
+var jst = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+// matchCategory reports whether the receipt matches the category parameter. s.mu must be held.
+func (s *Server) matchCategory(r apigen.Receipt, category string) bool {
+	switch apigen.GetReceiptsParamsCategory(category) {
+	case "", apigen.GetReceiptsParamsCategoryAll:
+		return r.Status != apigen.ReceiptStatusDeleted
+	case apigen.GetReceiptsParamsCategoryIgnored:
+		return r.Status == apigen.ReceiptStatusIgnored
+	case apigen.GetReceiptsParamsCategoryWithoutDeal:
+		return r.Status == apigen.ReceiptStatusConfirmed && !s.hasDeal(r.Id)
+	default:
+		return false
+	}
+}
+
+func (s *Server) handleCreateReceipt(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, "multipart/form-data で送信してください: %v", err)
+		return
+	}
+	s.mu.Lock()
+	companyID, ok := s.companyIDParam(w, r.FormValue("company_id"))
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	f, header, err := r.FormFile("receipt")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "receipt が指定されていません")
+		return
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "receipt を読み込めません: %v", err)
+		return
+	}
+
+	rc := apigen.Receipt{
+		CreatedAt: time.Now().In(jst).Format(time.RFC3339),
+		Status:    apigen.ReceiptStatusConfirmed,
+		Origin:    apigen.PublicApi,
+		MimeType:  http.DetectContentType(content),
+	}
+	if v := r.FormValue("description"); v != "" {
+		rc.Description = ptr(v)
+	}
+	if v := r.FormValue("document_type"); v != "" {
+		if !slices.Contains([]string{"receipt", "invoice", "other"}, v) {
+			writeError(w, http.StatusBadRequest, "document_type が不正です: %q", v)
+			return
+		}
+		rc.DocumentType = ptr(apigen.ReceiptDocumentType(v))
+	}
+	if v := r.FormValue("qualified_invoice"); v != "" {
+		if !slices.Contains([]string{"qualified", "not_qualified", "unselected"}, v) {
+			writeError(w, http.StatusBadRequest, "qualified_invoice が不正です: %q", v)
+			return
+		}
+		rc.QualifiedInvoice = ptr(apigen.ReceiptQualifiedInvoice(v))
+	}
+	m := &metadatum{}
+	if v := r.FormValue("receipt_metadatum_amount"); v != "" {
+		amount, ok := parseInt64(v)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "receipt_metadatum_amount が不正です: %q", v)
+			return
+		}
+		m.Amount = &amount
+	}
+	if v := r.FormValue("receipt_metadatum_issue_date"); v != "" {
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			writeError(w, http.StatusBadRequest, "receipt_metadatum_issue_date が不正です: %q", v)
+			return
+		}
+		m.IssueDate = ptr(v)
+	}
+	if v := r.FormValue("receipt_metadatum_partner_name"); v != "" {
+		m.PartnerName = ptr(v)
+	}
+	if *m != (metadatum{}) {
+		rc.ReceiptMetadatum = m
+	}
+
+	id := s.AddReceipt(companyID, rc, header.Filename, content)
+	created, _, _ := s.Receipt(id)
+	writeJSON(w, http.StatusCreated, apigen.ReceiptResponse{Receipt: created})
+}
+
+func (s *Server) handleGetReceipt(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rc, ok := s.receiptParam(w, r, r.URL.Query().Get("company_id"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, apigen.ReceiptResponse{Receipt: rc.receipt})
+}
+
+func (s *Server) handleUpdateReceipt(w http.ResponseWriter, r *http.Request) {
+	var params apigen.ReceiptUpdateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "リクエストボディが不正です: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rc, ok := s.receiptParam(w, r, params.CompanyId)
+	if !ok {
+		return
+	}
+	if params.Description != nil {
+		rc.receipt.Description = params.Description
+	}
+	if params.DocumentType != nil {
+		rc.receipt.DocumentType = ptr(apigen.ReceiptDocumentType(*params.DocumentType))
+	}
+	if params.QualifiedInvoice != nil {
+		rc.receipt.QualifiedInvoice = ptr(apigen.ReceiptQualifiedInvoice(*params.QualifiedInvoice))
+	}
+	if params.InvoiceRegistrationNumber != nil {
+		rc.receipt.InvoiceRegistrationNumber = params.InvoiceRegistrationNumber
+	}
+	if m := params.ReceiptMetadatum; m != nil {
+		rc.receipt.ReceiptMetadatum = newMetadatum(m.Amount, m.IssueDate, m.PartnerName)
+	}
+	writeJSON(w, http.StatusOK, apigen.ReceiptResponse{Receipt: rc.receipt})
+}
+
+func (s *Server) handleDestroyReceipt(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rc, ok := s.receiptParam(w, r, r.URL.Query().Get("company_id"))
+	if !ok {
+		return
+	}
+	rc.receipt.Status = apigen.ReceiptStatusDeleted
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDownloadReceipt(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rc, ok := s.receiptParam(w, r, r.URL.Query().Get("company_id"))
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", rc.receipt.MimeType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(rc.content)
+}
+
+// receiptParam returns the receipt specified by the {id} path parameter and
+// the company ID, which is either a raw query value or an int64.
+// It writes an error response if not found. s.mu must be held.
+func (s *Server) receiptParam(w http.ResponseWriter, r *http.Request, companyID any) (*receipt, bool) {
+	var cid int64
+	switch v := companyID.(type) {
+	case string:
+		var ok bool
+		if cid, ok = s.companyIDParam(w, v); !ok {
+			return nil, false
+		}
+	case int64:
+		if _, ok := s.findCompany(v); !ok {
+			writeError(w, http.StatusNotFound, "事業所が見つかりません: %d", v)
+			return nil, false
+		}
+		cid = v
+	}
+	id, ok := parseInt64(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "証憑IDが不正です: %q", r.PathValue("id"))
+		return nil, false
+	}
+	rc, ok := s.findReceipt(cid, id)
+	if !ok || rc.receipt.Status == apigen.ReceiptStatusDeleted {
+		writeError(w, http.StatusNotFound, "証憑が見つかりません: %d", id)
+		return nil, false
+	}
+	return rc, true
+}
@@ -0,0 +1,66 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// AddWalletTxn stores a bank or card transaction of the company, and returns its ID.
+// The ID and company ID of the given transaction are filled in by the server.
+func (s *Server) AddWalletTxn(companyID int64, t apigen.WalletTxn) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	t.Id = s.nextID
+	t.CompanyId = companyID
+	s.walletTxns = append(s.walletTxns, t)
+	return t.Id
+}
+
+func (s *Server) handleGetWalletTxns(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	companyID, ok := s.companyIDParam(w, q.Get("company_id"))
+	if !ok {
+		return
+	}
+	limit, offset := int64(20), int64(0)
+	if v := q.Get("limit"); v != "" {
+		if limit, ok = parseInt64(v); !ok || limit < 1 || limit > 100 {
+			writeError(w, http.StatusBadRequest, "limit が不正です: %q", v)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, ok = parseInt64(v); !ok || offset < 0 {
+			writeError(w, http.StatusBadRequest, "offset が不正です: %q", v)
+			return
+		}
+	}
+	// Dates are yyyy-mm-dd, so they can be compared as strings.
+	start, end := q.Get("start_date"), q.Get("end_date")
+	entrySide := q.Get("entry_side")
+	walletableType, walletableID := q.Get("walletable_type"), q.Get("walletable_id")
+	txns := []apigen.WalletTxn{}
+	for _, t := range s.walletTxns {
+		if t.CompanyId != companyID {
+			continue
+		}
+		if (start != "" && t.Date < start) || (end != "" && t.Date > end) {
+			continue
+		}
+		if entrySide != "" && string(t.EntrySide) != entrySide {
+			continue
+		}
+		if walletableType != "" && (string(t.WalletableType) != walletableType || walletableID != fmt.Sprint(t.WalletableId)) {
+			continue
+		}
+		txns = append(txns, t)
+	}
+	txns = txns[min(offset, int64(len(txns))):]
+	txns = txns[:min(limit, int64(len(txns)))]
+	writeJSON(w, http.StatusOK, map[string]any{"wallet_txns": txns})
+}
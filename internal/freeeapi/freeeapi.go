@@ -20,12 +20,11 @@ const APIEndpoint = "https://api.freee.co.jp/"
 
 type Client struct{ *apigen.ClientWithResponses }
 
-func NewClient(httpClient *http.Client) (*Client, error) {
-	client, err := apigen.NewClientWithResponses(
-		APIEndpoint,
-		apigen.WithHTTPClient(httpClient),
-		// TODO: add options...
-	)
+// NewClient creates a freee API client that sends requests with httpClient.
+// The base URL defaults to APIEndpoint, and can be changed with apigen.WithBaseURL.
+func NewClient(httpClient *http.Client, opts ...apigen.ClientOption) (*Client, error) {
+	opts = append([]apigen.ClientOption{apigen.WithHTTPClient(httpClient)}, opts...)
+	client, err := apigen.NewClientWithResponses(APIEndpoint, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("create freeeapi client: %w", err)
 	}
@@ -0,0 +1,37 @@
+package freeeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// NewManualJournalUpdateParams returns the parameters to update the manual journal j as it is.
+//
+// Like deals, the update API replaces the whole manual journal, so the
+// details and the attached receipts of j are carried over.
+func NewManualJournalUpdateParams(j *apigen.ManualJournal) (*apigen.ManualJournalUpdateParams, error) {
+	// The details of ManualJournal and ManualJournalUpdateParams are different
+	// anonymous structs sharing the JSON field names, so convert them through JSON.
+	b, err := json.Marshal(j)
+	if err != nil {
+		return nil, fmt.Errorf("marshal manual journal: %w", err)
+	}
+	var params apigen.ManualJournalUpdateParams
+	if err := json.Unmarshal(b, &params); err != nil {
+		return nil, fmt.Errorf("unmarshal manual journal update params: %w", err)
+	}
+	ids := ManualJournalReceiptIDs(j)
+	params.ReceiptIds = &ids
+	return &params, nil
+}
+
+// ManualJournalReceiptIDs returns the IDs of the receipts attached to the manual journal j.
+func ManualJournalReceiptIDs(j *apigen.ManualJournal) []int64 {
+	if j.ReceiptIds == nil {
+		return []int64{}
+	}
+	return slices.Clone(*j.ReceiptIds)
+}
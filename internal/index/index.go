@@ -0,0 +1,155 @@
+// Package index keeps a local copy of receipt metadata so that receipts can
+// be searched without calling the freee API.
+//
+// The index of each company is stored as a single file in a cache.Store. It
+// is fed with the receipts fetched by other commands, and is refreshed by
+// listing the receipts created since the last refresh.
+package index
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/micheam/freee-filebox-ctl/internal/cache"
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+// Index is the local index of the receipts of a company.
+type Index struct {
+	// RefreshedUntil is the date (yyyy-mm-dd) of the last refresh. Receipts
+	// created up to that date have been listed into the index.
+	RefreshedUntil string `json:"refreshed_until,omitempty"`
+	// Since is the earliest creation date (yyyy-mm-dd) covered by refreshes.
+	Since string `json:"since,omitempty"`
+	// Receipts are the indexed receipts by ID. Deleted receipts are kept
+	// with the deleted status.
+	Receipts map[int64]apigen.Receipt `json:"receipts"`
+}
+
+func key(companyID int64) string {
+	return fmt.Sprintf("index/%d", companyID)
+}
+
+// Load loads the index of the company from the store.
+// It returns an empty index if none is stored yet.
+func Load(store *cache.Store, companyID int64) (*Index, error) {
+	ix := &Index{}
+	if _, err := store.Get(key(companyID), 0, ix); err != nil {
+		return nil, err
+	}
+	if ix.Receipts == nil {
+		ix.Receipts = map[int64]apigen.Receipt{}
+	}
+	return ix, nil
+}
+
+// Save stores the index of the company.
+func (ix *Index) Save(store *cache.Store, companyID int64) error {
+	return store.Put(key(companyID), ix)
+}
+
+// Put adds the receipts to the index, replacing the ones with the same IDs.
+func (ix *Index) Put(receipts ...apigen.Receipt) {
+	for _, r := range receipts {
+		ix.Receipts[r.Id] = r
+	}
+}
+
+// MarkDeleted marks the receipt as deleted, if it is indexed.
+func (ix *Index) MarkDeleted(id int64) {
+	if r, ok := ix.Receipts[id]; ok {
+		r.Status = apigen.ReceiptStatusDeleted
+		ix.Receipts[id] = r
+	}
+}
+
+// Query is the condition of Search. Zero fields match any receipt.
+type Query struct {
+	// Partner matches receipts whose partner name contains it, ignoring case.
+	Partner string
+	// MinAmount and MaxAmount match receipts whose amount is in the range.
+	MinAmount, MaxAmount *int64
+	// StartIssueDate and EndIssueDate (yyyy-mm-dd) match receipts whose issue
+	// date is in the range.
+	StartIssueDate, EndIssueDate string
+	// Status matches receipts with the status. If empty, deleted receipts
+	// are excluded.
+	Status apigen.ReceiptStatus
+	// Text matches receipts whose description contains it, ignoring case.
+	Text string
+}
+
+// Search returns the indexed receipts that match q, ordered by issue date
+// (or creation date if not set) and then by ID.
+func (ix *Index) Search(q Query) []apigen.Receipt {
+	var found []apigen.Receipt
+	for _, r := range ix.Receipts {
+		if q.match(&r) {
+			found = append(found, r)
+		}
+	}
+	slices.SortFunc(found, func(a, b apigen.Receipt) int {
+		return cmp.Or(cmp.Compare(sortDate(&a), sortDate(&b)), cmp.Compare(a.Id, b.Id))
+	})
+	return found
+}
+
+func (q *Query) match(r *apigen.Receipt) bool {
+	if q.Status == "" {
+		if r.Status == apigen.ReceiptStatusDeleted {
+			return false
+		}
+	} else if r.Status != q.Status {
+		return false
+	}
+	var amount *int64
+	var issueDate, partner string
+	if m := r.ReceiptMetadatum; m != nil {
+		amount = m.Amount
+		issueDate = deref(m.IssueDate)
+		partner = deref(m.PartnerName)
+	}
+	if q.Partner != "" && !containsFold(partner, q.Partner) {
+		return false
+	}
+	if q.Text != "" && !containsFold(deref(r.Description), q.Text) {
+		return false
+	}
+	if q.MinAmount != nil && (amount == nil || *amount < *q.MinAmount) {
+		return false
+	}
+	if q.MaxAmount != nil && (amount == nil || *amount > *q.MaxAmount) {
+		return false
+	}
+	// Issue dates are yyyy-mm-dd, so they can be compared as strings.
+	if q.StartIssueDate != "" && (issueDate == "" || issueDate < q.StartIssueDate) {
+		return false
+	}
+	if q.EndIssueDate != "" && (issueDate == "" || issueDate > q.EndIssueDate) {
+		return false
+	}
+	return true
+}
+
+// sortDate returns the issue date of the receipt, or its creation time if not set.
+// Both start with yyyy-mm-dd, so they can be compared as strings.
+func sortDate(r *apigen.Receipt) string {
+	if m := r.ReceiptMetadatum; m != nil && deref(m.IssueDate) != "" {
+		return *m.IssueDate
+	}
+	return r.CreatedAt
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
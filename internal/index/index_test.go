@@ -0,0 +1,67 @@
+package index
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/micheam/freee-filebox-ctl/internal/cache"
+	apigen "github.com/micheam/freee-filebox-ctl/internal/freeeapi/gen"
+)
+
+func newReceipt(id int64, amount int64, issueDate, partner, description string) apigen.Receipt {
+	r := apigen.Receipt{Id: id, Status: apigen.ReceiptStatusConfirmed, CreatedAt: "2025-01-01T00:00:00+09:00", Description: &description}
+	r.ReceiptMetadatum = &struct {
+		Amount      *int64  `json:"amount"`
+		IssueDate   *string `json:"issue_date"`
+		PartnerName *string `json:"partner_name"`
+	}{&amount, &issueDate, &partner}
+	return r
+}
+
+func TestSearch(t *testing.T) {
+	store := cache.New(t.TempDir())
+	ix, err := Load(store, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.Put(
+		newReceipt(1, 1100, "2025-04-01", "テスト文具店", "ボールペン"),
+		newReceipt(2, 5500, "2025-03-15", "Sample Cafe", "打ち合わせ"),
+		newReceipt(3, 330, "2025-05-20", "テスト文具店", "付箋"),
+	)
+	ix.MarkDeleted(3)
+	if err := ix.Save(store, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ix, err = Load(store, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(rs []apigen.Receipt) []int64 {
+		var ids []int64
+		for _, r := range rs {
+			ids = append(ids, r.Id)
+		}
+		return ids
+	}
+	minAmount, maxAmount := int64(1000), int64(2000)
+	tests := []struct {
+		name string
+		q    Query
+		want []int64
+	}{
+		{"all ordered by issue date", Query{}, []int64{2, 1}},
+		{"partner", Query{Partner: "文具"}, []int64{1}},
+		{"partner ignoring case", Query{Partner: "sample"}, []int64{2}},
+		{"amount range", Query{MinAmount: &minAmount, MaxAmount: &maxAmount}, []int64{1}},
+		{"issue date range", Query{StartIssueDate: "2025-04-01", EndIssueDate: "2025-12-31"}, []int64{1}},
+		{"deleted", Query{Status: apigen.ReceiptStatusDeleted}, []int64{3}},
+		{"description", Query{Text: "打ち合わせ"}, []int64{2}},
+		{"no match", Query{Partner: "文具", Text: "打ち合わせ"}, nil},
+	}
+	for _, tt := range tests {
+		if got := ids(ix.Search(tt.q)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Search() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Package invoice validates the registration numbers of qualified invoice
+// issuers (適格請求書発行事業者登録番号, T番号) of the Japanese qualified invoice
+// system.
+//
+// A registration number is "T" followed by 13 digits. For corporations the
+// digits are the corporate number (法人番号), and numbers issued to
+// individuals follow the same scheme, so the first digit is a check digit
+// of the remaining 12 digits.
+package invoice
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrFormat is returned when a number is not "T" followed by 13 digits.
+	ErrFormat = errors.New("registration number must be T followed by 13 digits")
+	// ErrCheckDigit is returned when the check digit does not match.
+	ErrCheckDigit = errors.New("check digit of registration number does not match")
+)
+
+// ValidateRegistrationNumber checks the format and the check digit of the
+// registration number s. The returned error wraps ErrFormat or ErrCheckDigit.
+func ValidateRegistrationNumber(s string) error {
+	if len(s) != 14 || s[0] != 'T' {
+		return fmt.Errorf("%w: %q", ErrFormat, s)
+	}
+	digits := s[1:]
+	for i := range len(digits) {
+		if digits[i] < '0' || '9' < digits[i] {
+			return fmt.Errorf("%w: %q", ErrFormat, s)
+		}
+	}
+	if want := CheckDigit(digits[1:]); int(digits[0]-'0') != want {
+		return fmt.Errorf("%w: %q (want %d)", ErrCheckDigit, s, want)
+	}
+	return nil
+}
+
+// CheckDigit returns the check digit of the 12-digit base number of a
+// corporate number: 9 - (Σ Pn × Qn mod 9), where Pn is the n-th digit from
+// the right and Qn is 1 if n is odd and 2 if n is even.
+// base must consist of 12 ASCII digits.
+func CheckDigit(base string) int {
+	sum := 0
+	for n := 1; n <= len(base); n++ {
+		p := int(base[len(base)-n] - '0')
+		if n%2 == 0 {
+			p *= 2
+		}
+		sum += p
+	}
+	return 9 - sum%9
+}
@@ -0,0 +1,29 @@
+package invoice
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateRegistrationNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+	}{
+		// The corporate number of the National Tax Agency.
+		{"T7000012050002", nil},
+		{"T1234567890123", ErrCheckDigit},
+		{"T8000012050002", ErrCheckDigit},
+		{"7000012050002", ErrFormat},
+		{"t7000012050002", ErrFormat},
+		{"T700001205000", ErrFormat},
+		{"T70000120500021", ErrFormat},
+		{"T70000120500O2", ErrFormat},
+		{"", ErrFormat},
+	}
+	for _, tt := range tests {
+		if err := ValidateRegistrationNumber(tt.in); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("ValidateRegistrationNumber(%q) = %v, want %v", tt.in, err, tt.want)
+		}
+	}
+}
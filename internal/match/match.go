@@ -0,0 +1,207 @@
+// Package match suggests pairs of receipts and bank or card transactions
+// that likely record the same payment.
+//
+// Each pair is scored on three signals: whether the amounts are equal, how
+// close the issue date of the receipt is to the transaction date, and how
+// similar the partner name of the receipt is to the transaction description.
+package match
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Weights of the signals in the total score. They sum up to 1.
+const (
+	amountWeight = 0.5
+	dateWeight   = 0.3
+	nameWeight   = 0.2
+)
+
+// Receipt is a receipt to be matched.
+type Receipt struct {
+	ID          int64
+	Amount      int64
+	IssueDate   time.Time
+	PartnerName string
+}
+
+// Txn is a bank or card transaction to be matched.
+type Txn struct {
+	ID          int64
+	Amount      int64
+	Date        time.Time
+	Description string
+}
+
+// Options controls the scoring.
+type Options struct {
+	// MaxDays is the number of days between the issue date and the transaction
+	// date at which the date score drops to zero. If zero, 7 is used.
+	MaxDays int
+	// MinScore is the minimum total score of suggested pairs.
+	MinScore float64
+}
+
+// Suggestion is a suggested pair of a receipt and a transaction.
+type Suggestion struct {
+	Receipt Receipt
+	Txn     Txn
+	// Score is the weighted total of the signal scores, from 0 to 1.
+	Score float64
+	// AmountScore, DateScore and NameScore are the scores of each signal, from 0 to 1.
+	AmountScore float64
+	DateScore   float64
+	NameScore   float64
+}
+
+// Score scores the pair of r and t.
+func Score(r Receipt, t Txn, opts Options) Suggestion {
+	s := Suggestion{
+		Receipt:     r,
+		Txn:         t,
+		AmountScore: amountScore(r.Amount, t.Amount),
+		DateScore:   dateScore(r.IssueDate, t.Date, cmp.Or(opts.MaxDays, 7)),
+		NameScore:   NameSimilarity(r.PartnerName, t.Description),
+	}
+	s.Score = amountWeight*s.AmountScore + dateWeight*s.DateScore + nameWeight*s.NameScore
+	return s
+}
+
+// Suggest scores every pair of receipts and txns, and returns the pairs whose
+// score is at least opts.MinScore, highest score first.
+//
+// Each receipt and each transaction appears in at most one suggestion: the
+// pairs are taken greedily in the order of their scores.
+func Suggest(receipts []Receipt, txns []Txn, opts Options) []Suggestion {
+	var all []Suggestion
+	for _, r := range receipts {
+		for _, t := range txns {
+			if s := Score(r, t, opts); s.Score >= opts.MinScore {
+				all = append(all, s)
+			}
+		}
+	}
+	slices.SortStableFunc(all, func(a, b Suggestion) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	usedReceipts := make(map[int64]bool)
+	usedTxns := make(map[int64]bool)
+	var suggestions []Suggestion
+	for _, s := range all {
+		if usedReceipts[s.Receipt.ID] || usedTxns[s.Txn.ID] {
+			continue
+		}
+		usedReceipts[s.Receipt.ID] = true
+		usedTxns[s.Txn.ID] = true
+		suggestions = append(suggestions, s)
+	}
+	return suggestions
+}
+
+// amountScore returns 1 for equal amounts, 0.5 for amounts within 1% of
+// each other (such as a foreign currency payment), and 0 otherwise.
+func amountScore(a, b int64) float64 {
+	switch d := abs(a - b); {
+	case d == 0:
+		return 1
+	case d*100 <= max(abs(a), abs(b)):
+		return 0.5
+	}
+	return 0
+}
+
+// dateScore decreases linearly from 1 for the same day to 0 at maxDays apart.
+func dateScore(a, b time.Time, maxDays int) float64 {
+	if a.IsZero() || b.IsZero() {
+		return 0
+	}
+	days := abs(int64(a.Sub(b).Hours() / 24))
+	if days >= int64(maxDays) {
+		return 0
+	}
+	return 1 - float64(days)/float64(maxDays)
+}
+
+// corporateDesignators are removed from names before comparison, because
+// statements often omit or abbreviate them.
+var corporateDesignators = []string{
+	"株式会社", "有限会社", "合同会社", "(株)", "(有)", "(同)", "㈱", "㈲",
+	"inc.", "inc", "co.,ltd.", "co.,ltd", "co.", "ltd.", "ltd", "llc", "corp.", "corp",
+}
+
+// NameSimilarity returns how similar the two names are, from 0 to 1.
+//
+// The names are normalized by folding full-width alphanumerics to half-width,
+// lower-casing, and removing spaces, punctuation and corporate designators
+// such as "株式会社". A name contained in the other scores 1; otherwise the
+// score is the Dice coefficient of their character bigrams.
+func NameSimilarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if strings.Contains(a, b) || strings.Contains(b, a) {
+		return 1
+	}
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+	var common int
+	for g, n := range ba {
+		common += min(n, bb[g])
+	}
+	return 2 * float64(common) / float64(total(ba)+total(bb))
+}
+
+func normalizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '！' && r <= '～':
+			return r - '！' + '!'
+		case r == '　':
+			return ' '
+		}
+		return r
+	}, s)
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, " ", "")
+	for _, d := range corporateDesignators {
+		s = strings.ReplaceAll(s, d, "")
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSpace(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func bigrams(s string) map[string]int {
+	rs := []rune(s)
+	m := make(map[string]int)
+	for i := 0; i+1 < len(rs); i++ {
+		m[string(rs[i:i+2])]++
+	}
+	return m
+}
+
+func total(m map[string]int) int {
+	var n int
+	for _, v := range m {
+		n += v
+	}
+	return n
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,53 @@
+package match
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"株式会社テスト文具店", "テスト文具店", 1},
+		{"Ａｍａｚｏｎ　Ｗｅｂ　Ｓｅｒｖｉｃｅｓ", "AMAZON WEB SERVICES", 1},
+		{"Anthropic, PBC", "ANTHROPIC", 1},
+		{"Google Cloud", "", 0},
+		{"ABCD", "WXYZ", 0},
+	}
+	for _, tt := range tests {
+		if got := NameSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("NameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if got := NameSimilarity("デジタル文具", "テスト文具店"); got <= 0 || got >= 1 {
+		t.Errorf("NameSimilarity of partially matching names = %v, want between 0 and 1", got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 4, d, 0, 0, 0, 0, time.UTC) }
+	receipts := []Receipt{
+		{ID: 1, Amount: 1100, IssueDate: day(1), PartnerName: "テスト文具店"},
+		{ID: 2, Amount: 1100, IssueDate: day(10), PartnerName: "サンプル商事"},
+		{ID: 3, Amount: 99999, IssueDate: day(20), PartnerName: "無関係"},
+	}
+	txns := []Txn{
+		{ID: 10, Amount: 1100, Date: day(11), Description: "ｻﾝﾌﾟﾙ ショウジ"},
+		{ID: 11, Amount: 1100, Date: day(2), Description: "VISA テスト文具店"},
+	}
+	got := Suggest(receipts, txns, Options{MinScore: 0.5})
+	if len(got) != 2 {
+		t.Fatalf("Suggest() returned %d suggestions, want 2: %+v", len(got), got)
+	}
+	if got[0].Receipt.ID != 1 || got[0].Txn.ID != 11 {
+		t.Errorf("best suggestion = receipt %d / txn %d, want 1 / 11", got[0].Receipt.ID, got[0].Txn.ID)
+	}
+	if got[1].Receipt.ID != 2 || got[1].Txn.ID != 10 {
+		t.Errorf("second suggestion = receipt %d / txn %d, want 2 / 10", got[1].Receipt.ID, got[1].Txn.ID)
+	}
+	if got[0].Score <= got[1].Score {
+		t.Errorf("suggestions are not ranked: %v <= %v", got[0].Score, got[1].Score)
+	}
+}
@@ -0,0 +1,247 @@
+// Package rules classifies receipts with user-defined rules loaded from a
+// TOML file.
+//
+// Each rule has match conditions and actions:
+//
+//	[[rule]]
+//	name = "aws"
+//	[rule.match]
+//	partner = "(?i)amazon web services"
+//	min_amount = 1000
+//	[rule.set]
+//	document_type = "invoice"
+//	qualified_invoice = "qualified"
+//	tags = ["cloud"]
+//
+// A rule matches when all of its conditions hold; a rule without conditions
+// matches every receipt. Rules are evaluated in order, and each field is set
+// by the first matching rule that sets it. Tags are collected from all
+// matching rules.
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Rules is a list of classification rules.
+type Rules struct {
+	Rules []Rule `toml:"rule"`
+}
+
+// Rule is a classification rule.
+type Rule struct {
+	// Name identifies the rule in messages. Defaults to "#<index>".
+	Name  string `toml:"name"`
+	Match Match  `toml:"match"`
+	Set   Action `toml:"set"`
+
+	partner *regexp.Regexp
+}
+
+// Match holds the conditions of a rule. Empty fields are not checked.
+type Match struct {
+	// Partner is a regular expression matched against the partner name.
+	Partner string `toml:"partner"`
+	// MinAmount and MaxAmount are the inclusive range of the amount.
+	MinAmount *int64 `toml:"min_amount"`
+	MaxAmount *int64 `toml:"max_amount"`
+	// MimeType is a path.Match pattern of the MIME type, such as "image/*".
+	MimeType string `toml:"mime_type"`
+	// Filename is a path.Match pattern of the file name, such as "*_aws_*.pdf".
+	// The file name is only known on upload.
+	Filename string `toml:"filename"`
+	// Origin is the upload origin, such as "mail" or "public_api".
+	Origin string `toml:"origin"`
+}
+
+// Action holds the values a rule sets. Empty fields are not set.
+type Action struct {
+	// DocumentType is one of "receipt", "invoice" and "other".
+	DocumentType string `toml:"document_type"`
+	// QualifiedInvoice is one of "qualified", "not_qualified" and "unselected".
+	QualifiedInvoice string `toml:"qualified_invoice"`
+	// Description replaces the description of the receipt.
+	Description string `toml:"description"`
+	// Tags are added to the description as hashtags ("#tag"), since receipts
+	// have no tag field.
+	Tags []string `toml:"tags"`
+}
+
+// Target is a receipt to classify.
+type Target struct {
+	Partner  string
+	Amount   *int64
+	MimeType string
+	Filename string
+	Origin   string
+}
+
+// Result is the outcome of applying rules to a target.
+type Result struct {
+	Action
+	// Rules are the names of the matched rules.
+	Rules []string
+}
+
+// Load reads the rules from the TOML file at path.
+func Load(path string) (*Rules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse parses the rules in TOML and validates them.
+func Parse(b []byte) (*Rules, error) {
+	rs := &Rules{}
+	dec := toml.NewDecoder(bytes.NewReader(b)).DisallowUnknownFields()
+	if err := dec.Decode(rs); err != nil {
+		var strict *toml.StrictMissingError
+		if errors.As(err, &strict) {
+			return nil, fmt.Errorf("parse rules: %s", strict.String())
+		}
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("#%d", i+1)
+		}
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+	}
+	return rs, nil
+}
+
+func (r *Rule) compile() error {
+	m, a := &r.Match, &r.Set
+	if m.Partner != "" {
+		re, err := regexp.Compile(m.Partner)
+		if err != nil {
+			return fmt.Errorf("invalid partner pattern: %w", err)
+		}
+		r.partner = re
+	}
+	for _, p := range []string{m.MimeType, m.Filename} {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	switch a.DocumentType {
+	case "", "receipt", "invoice", "other":
+	default:
+		return fmt.Errorf("invalid document_type: %q", a.DocumentType)
+	}
+	switch a.QualifiedInvoice {
+	case "", "qualified", "not_qualified", "unselected":
+	default:
+		return fmt.Errorf("invalid qualified_invoice: %q", a.QualifiedInvoice)
+	}
+	if utf8.RuneCountInString(a.Description) > MaxDescriptionLength {
+		return fmt.Errorf("description is longer than %d characters", MaxDescriptionLength)
+	}
+	for _, tag := range a.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t\n#") {
+			return fmt.Errorf("invalid tag: %q", tag)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) match(t *Target) bool {
+	m := &r.Match
+	if r.partner != nil && (t.Partner == "" || !r.partner.MatchString(t.Partner)) {
+		return false
+	}
+	if m.MinAmount != nil && (t.Amount == nil || *t.Amount < *m.MinAmount) {
+		return false
+	}
+	if m.MaxAmount != nil && (t.Amount == nil || *t.Amount > *m.MaxAmount) {
+		return false
+	}
+	if m.MimeType != "" && !matchPattern(m.MimeType, t.MimeType) {
+		return false
+	}
+	if m.Filename != "" && !matchPattern(m.Filename, t.Filename) {
+		return false
+	}
+	if m.Origin != "" && m.Origin != t.Origin {
+		return false
+	}
+	return true
+}
+
+func matchPattern(pattern, s string) bool {
+	ok, _ := path.Match(pattern, s) // validated by compile
+	return s != "" && ok
+}
+
+// Apply applies the rules to t. It returns false if no rule matches.
+func (rs *Rules) Apply(t Target) (Result, bool) {
+	var res Result
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if !r.match(&t) {
+			continue
+		}
+		res.Rules = append(res.Rules, r.Name)
+		if res.DocumentType == "" {
+			res.DocumentType = r.Set.DocumentType
+		}
+		if res.QualifiedInvoice == "" {
+			res.QualifiedInvoice = r.Set.QualifiedInvoice
+		}
+		if res.Description == "" {
+			res.Description = r.Set.Description
+		}
+		for _, tag := range r.Set.Tags {
+			if !slices.Contains(res.Tags, tag) {
+				res.Tags = append(res.Tags, tag)
+			}
+		}
+	}
+	return res, len(res.Rules) > 0
+}
+
+// MaxDescriptionLength is the maximum number of characters of a receipt
+// description accepted by the freee API.
+const MaxDescriptionLength = 255
+
+// Describe returns the description of a receipt whose current description
+// is current: the Description of the result if set, or current, followed by
+// the hashtags of the tags it does not contain yet.
+//
+// Hashtags that would make the description longer than MaxDescriptionLength
+// characters are left out. The description itself is never shortened.
+func (res *Result) Describe(current string) string {
+	desc := current
+	if res.Description != "" {
+		desc = res.Description
+	}
+	words := strings.Fields(desc)
+	for _, tag := range res.Tags {
+		hashtag := "#" + tag
+		if slices.Contains(words, hashtag) {
+			continue
+		}
+		next := strings.TrimSpace(desc + " " + hashtag)
+		if utf8.RuneCountInString(next) > MaxDescriptionLength {
+			continue
+		}
+		desc = next
+		words = append(words, hashtag)
+	}
+	return desc
+}
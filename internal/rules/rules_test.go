@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+[[rule]]
+name = "aws"
+[rule.match]
+partner = "(?i)amazon web services"
+min_amount = 1000
+[rule.set]
+document_type = "invoice"
+qualified_invoice = "qualified"
+tags = ["cloud"]
+
+[[rule]]
+name = "scans"
+[rule.match]
+mime_type = "image/*"
+filename = "scan_*"
+[rule.set]
+document_type = "receipt"
+description = "スキャン"
+tags = ["scan"]
+
+[[rule]]
+[rule.match]
+origin = "mail"
+[rule.set]
+qualified_invoice = "unselected"
+tags = ["mail", "cloud"]
+`
+
+func TestApply(t *testing.T) {
+	rs, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := func(v int64) *int64 { return &v }
+	tests := []struct {
+		name   string
+		target Target
+		want   Result
+		ok     bool
+	}{
+		{
+			name:   "partner and amount",
+			target: Target{Partner: "Amazon Web Services Japan", Amount: amount(5000), Origin: "mail"},
+			want: Result{
+				Action: Action{DocumentType: "invoice", QualifiedInvoice: "qualified", Tags: []string{"cloud", "mail"}},
+				Rules:  []string{"aws", "#3"},
+			},
+			ok: true,
+		},
+		{
+			name:   "amount out of range",
+			target: Target{Partner: "Amazon Web Services Japan", Amount: amount(500)},
+		},
+		{
+			name:   "mime type and filename",
+			target: Target{MimeType: "image/jpeg", Filename: "scan_001.jpg"},
+			want:   Result{Action: Action{DocumentType: "receipt", Description: "スキャン", Tags: []string{"scan"}}, Rules: []string{"scans"}},
+			ok:     true,
+		},
+		{
+			name:   "unknown filename",
+			target: Target{MimeType: "image/jpeg"},
+		},
+	}
+	for _, tt := range tests {
+		got, ok := rs.Apply(tt.target)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Apply() = %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	res := Result{Action: Action{Tags: []string{"cloud", "mail"}}}
+	if got := res.Describe("AWS #cloud"); got != "AWS #cloud #mail" {
+		t.Errorf("Describe() = %q", got)
+	}
+	if got := res.Describe(""); got != "#cloud #mail" {
+		t.Errorf("Describe() = %q", got)
+	}
+	res.Description = "AWS"
+	if got := res.Describe("old"); got != "AWS #cloud #mail" {
+		t.Errorf("Describe() = %q", got)
+	}
+
+	// Hashtags that do not fit in the limit are left out.
+	res.Description = ""
+	long := strings.Repeat("あ", MaxDescriptionLength-7)
+	if got := res.Describe(long); got != long+" #cloud" {
+		t.Errorf("Describe() of a long description = %q", got)
+	}
+	// A description longer than the limit is kept as is.
+	tooLong := long + "いいいいいいいいいい"
+	if got := res.Describe(tooLong); got != tooLong {
+		t.Errorf("Describe() of a too long description = %q, want it unchanged", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"[[rule]]\n[rule.match]\npartner = \"(\"",
+		"[[rule]]\n[rule.set]\ndocument_type = \"bill\"",
+		"[[rule]]\n[rule.match]\nvendor = \"x\"",
+		"[[rule]]\n[rule.set]\ntags = [\"a b\"]",
+		"[[rule]]\n[rule.set]\ndescription = \"" + strings.Repeat("あ", MaxDescriptionLength+1) + "\"",
+	} {
+		if _, err := Parse([]byte(in)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", in)
+		}
+	}
+}